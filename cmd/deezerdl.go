@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/docopt/docopt-go"
@@ -14,13 +13,96 @@ const VERSION = "0.2.4"
 const doc = `deezerdl
 
 Usage:
-  deezerdl login <arl>
-  deezerdl download track <ID> [-f <fmt> | --format=<fmt>]
-  deezerdl download album <ID> [-f <fmt> | --format=<fmt>]
-  deezerdl config set DefaultFormat <fmt>
+  deezerdl login [<arl>] [--profile=<name>]
+  deezerdl whoami [--profile=<name>]
+  deezerdl download track <ID> [-f <fmt> | --format=<fmt>] [--force] [--dry-run] [--json] [--limit-rate=<rate>] [--no-cache] [--profile=<name>]
+  deezerdl download album <ID> [-f <fmt> | --format=<fmt>] [--force] [--dry-run] [--json] [-j <n> | --jobs=<n>] [--limit-rate=<rate>] [--no-cache] [--profile=<name>]
+  deezerdl download playlist <ID> [-f <fmt> | --format=<fmt>] [--force] [--dry-run] [--json] [-j <n> | --jobs=<n>] [--limit-rate=<rate>] [--no-cache] [--profile=<name>]
+  deezerdl download --from-file=<file> [-f <fmt> | --format=<fmt>] [--force] [--dry-run] [--json] [-j <n> | --jobs=<n>] [--limit-rate=<rate>] [--no-cache] [--profile=<name>]
+  deezerdl info (track|album|artist) <ID> [--json] [--no-cache] [--profile=<name>]
+  deezerdl search [track|album|artist|playlist] <query>... [--limit=<n>] [--page=<n>] [--select=<list>] [--ids | --json]
+  deezerdl config set <key> <value> [--profile=<name>]
+  deezerdl profile (use|add|remove) <name>
+  deezerdl profile list
+  deezerdl archive (list|prune)
+  deezerdl archive import <dir>
+  deezerdl cache (stats|clear)
 
 Options:
   -f --format=<fmt>    Specifies the download format. Valid options are FLAC, MP3_320, MP3_256.
+  --from-file=<file>   Download every item listed in a file, or stdin if the file is -.
+  --force              Download tracks even if the archive says they have already been downloaded.
+  --dry-run            Print what would be downloaded, and where, without downloading anything.
+  -j --jobs=<n>        Number of tracks of an album or playlist to download at once [default: 1].
+  --limit-rate=<rate>  Limit the total download speed of all jobs, e.g. 2MiB/s.
+  --json               Print newline-delimited JSON events on stdout instead of text.
+  --profile=<name>     Use the named profile for this run instead of the active one.
+  --no-cache           Neither read nor write the metadata cache for this run.
+  --limit=<n>          Number of search results per page [default: 10].
+  --page=<n>           Page of search results to show [default: 1].
+  --select=<list>      Only show the search results with these numbers, e.g. 1,3-5.
+  --ids                Print search results as "<type> <ID>" lines.
+
+If <arl> is omitted from login, it is prompted for on a terminal or
+read from stdin.
+
+info prints the metadata of a track or album without downloading it,
+or an artist's releases. Its <ID> can also be a deezer URL.
+
+search looks for tracks unless another type is given. Its --ids output
+can be piped into download, e.g.
+  deezerdl search album discovery --select=1 --ids | deezerdl download --from-file=-
+Artists can't be downloaded, so --ids can't be used for artist searches;
+use info artist <ID> to list their releases.
+
+Each line of a --from-file list is a deezer URL or "track|album|playlist
+<ID>", optionally followed by a format for that item. Blank lines and
+text after a # are ignored.
+
+Every downloaded track is recorded in an archive in the config dir and
+skipped next time. "archive prune" forgets files that have since been
+moved or deleted, and "archive import" rebuilds the archive from a
+library of files downloaded by deezerdl. Only files with the
+DEEZER_SNG_ID tag can be imported; versions of deezerdl before the
+archive did not tag their files, so those have to be downloaded again.
+
+Track, album, artist and playlist metadata is cached in the user cache
+dir (e.g. ~/.cache/deezerdl) so that it is not fetched again on every
+run. Songs and albums are kept for a week, artists for a day and
+playlists for an hour. Song data depends on the account, so each profile
+has its own, and it is dropped if a download fails. "cache stats" shows
+what is cached and "cache clear" empties it.
+
+Config keys:
+  DefaultFormat    Format used when --format is not given.
+  OutputDir        Directory that tracks and albums are saved in.
+  TrackTemplate    Go template for track filenames, without extension.
+                   Fields: .Title .ID .TrackNumber .DiscNumber .TotalDiscs
+                   .Album .AlbumID .Format
+  AlbumTemplate    Go template for album directory names.
+  DiscLayout       How tracks of albums with more than one disc are kept
+                   apart: prefix (the default, "1-01 - Title"), folders
+                   (CD1, CD2, ...) or none (use .DiscNumber in TrackTemplate).
+  ArtistSeparator  Joins the names in artist and credit tags with more than
+                   one name, e.g. "; ". Empty (the default) writes each
+                   name as a separate value.
+  LyricsFile       Save synchronised lyrics next to each track as an .lrc
+                   file: true or false (the default).
+  EmbedLyrics      Write lyrics into the tags of each track: true or false
+                   (the default).
+  SecretStore      Where arl cookies are kept, for all profiles: file
+                   (the default, credentials.json in the config dir) or
+                   env (DEEZERDL_ARL, or DEEZERDL_ARL_<PROFILE>).
+  Cache            Whether to cache metadata, for all profiles: true (the
+                   default) or false.
+  CacheMaxSize     Size limit of the metadata cache, e.g. 50MB (the
+                   default is 100MiB).
+  LimitRate        Total download speed limit used when --limit-rate is not
+                   given, for all profiles, e.g. 2MiB/s.
+  GatewayRateLimit Requests per second to the private gateway (default 5),
+  PublicRateLimit  the public API (default 10) and the CDN that tracks
+  CDNRateLimit     are downloaded from (default 0). 0 means no limit. The
+                   limits are for all profiles and shared by all jobs.
 `
 
 var config *internal.Configuration
@@ -57,17 +139,34 @@ func main() {
 		}
 	}
 
+	// select the profile for this run
+	if name, err := opts.String("--profile"); err == nil && name != "" {
+		if err := config.UseProfile(name); err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
+	// skip the cache for this run
+	if noCache, err := opts.Bool("--no-cache"); err == nil && noCache {
+		config.SkipCache()
+	}
+
 	// login method
 	if _, ok := opts["login"]; ok {
 		if login, err := opts.Bool("login"); err != nil {
 			logrus.Fatalf("failed to parse args: %s", err)
 		} else if login {
-			config.ARLCookie, err = opts.String("<arl>")
-			if err != nil {
-				logrus.Fatalf("failed to set arl cookie: %s", err)
-			}
-			config.SaveConfig()
-			fmt.Println("Saved arl! You can now use the rest of the program.")
+			internal.Login(opts, config)
+			return
+		}
+	}
+
+	// whoami method
+	if _, ok := opts["whoami"]; ok {
+		if whoami, err := opts.Bool("whoami"); err != nil {
+			logrus.Fatalf("failed to parse args: %s", err)
+		} else if whoami {
+			internal.WhoAmI(opts, config)
 			return
 		}
 	}
@@ -82,6 +181,56 @@ func main() {
 		}
 	}
 
+	// info method
+	if _, ok := opts["info"]; ok {
+		if info, err := opts.Bool("info"); err != nil {
+			logrus.Fatalf("failed to parse args: %s", err)
+		} else if info {
+			internal.Info(opts, config)
+			return
+		}
+	}
+
+	// search method
+	if _, ok := opts["search"]; ok {
+		if search, err := opts.Bool("search"); err != nil {
+			logrus.Fatalf("failed to parse args: %s", err)
+		} else if search {
+			internal.Search(opts, config)
+			return
+		}
+	}
+
+	// profile method
+	if _, ok := opts["profile"]; ok {
+		if profile, err := opts.Bool("profile"); err != nil {
+			logrus.Fatalf("failed to parse args: %s", err)
+		} else if profile {
+			internal.ProfileCommand(opts, config)
+			return
+		}
+	}
+
+	// archive method
+	if _, ok := opts["archive"]; ok {
+		if archive, err := opts.Bool("archive"); err != nil {
+			logrus.Fatalf("failed to parse args: %s", err)
+		} else if archive {
+			internal.ArchiveCommand(opts, config)
+			return
+		}
+	}
+
+	// cache method
+	if _, ok := opts["cache"]; ok {
+		if c, err := opts.Bool("cache"); err != nil {
+			logrus.Fatalf("failed to parse args: %s", err)
+		} else if c {
+			internal.CacheCommand(opts, config)
+			return
+		}
+	}
+
 	// config method
 	if _, ok := opts["config"]; ok {
 		if cfg, err := opts.Bool("config"); err != nil {
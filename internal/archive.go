@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docopt/docopt-go"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/joshbarrass/deezerdl/pkg/tags"
+	"github.com/sirupsen/logrus"
+)
+
+const archiveFile = "archive.json"
+
+// Tags written to every downloaded file so that it can be recognised
+// when importing an existing library into the archive
+const (
+	songIDTag = "DEEZER_SNG_ID"
+	formatTag = "DEEZER_FORMAT"
+)
+
+// ArchiveEntry records a downloaded track
+type ArchiveEntry struct {
+	ID       int       `json:"id"`
+	Format   string    `json:"format"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Checksum string    `json:"sha256"`
+	Added    time.Time `json:"added"`
+}
+
+// Archive stores all tracks that have been downloaded, keyed by
+// SNG_ID and format, so that they can be skipped next time
+type Archive struct {
+	Entries map[string]*ArchiveEntry `json:"entries"`
+	path    string
+	mu      sync.Mutex
+}
+
+// ArchiveKey returns the key of a track in the archive
+func ArchiveKey(ID int, format deezer.Format) string {
+	return fmt.Sprintf("%d:%s", ID, FormatToFormatString(format))
+}
+
+// LoadArchive loads the archive from the config dir, returning an
+// empty archive if it does not exist yet
+func LoadArchive() (*Archive, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	archive := &Archive{
+		Entries: make(map[string]*ArchiveEntry),
+		path:    filepath.Join(os.ExpandEnv(configDir), archiveFile),
+	}
+
+	inFile, err := os.Open(archive.path)
+	if os.IsNotExist(err) {
+		return archive, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer inFile.Close()
+
+	if err := json.NewDecoder(inFile).Decode(archive); err != nil {
+		return nil, err
+	}
+	if archive.Entries == nil {
+		archive.Entries = make(map[string]*ArchiveEntry)
+	}
+	return archive, nil
+}
+
+// Save writes the archive to the config dir, replacing the old file
+// only once the new one is complete
+func (archive *Archive) Save() error {
+	archive.mu.Lock()
+	defer archive.mu.Unlock()
+
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(archive.path, append(data, '\n'), configFilePerms)
+}
+
+// Lookup finds a track in the archive. The entry is only returned if
+// its file still exists with the recorded size.
+func (archive *Archive) Lookup(ID int, format deezer.Format) (*ArchiveEntry, bool) {
+	archive.mu.Lock()
+	defer archive.mu.Unlock()
+	entry, ok := archive.Entries[ArchiveKey(ID, format)]
+	if !ok || !entry.Valid() {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Add records a downloaded file in the archive, calculating its size
+// and checksum
+func (archive *Archive) Add(ID int, format deezer.Format, path string) (*ArchiveEntry, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	size, checksum, err := checksumFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := &ArchiveEntry{
+		ID:       ID,
+		Format:   FormatToFormatString(format),
+		Path:     path,
+		Size:     size,
+		Checksum: checksum,
+		Added:    time.Now(),
+	}
+
+	archive.mu.Lock()
+	defer archive.mu.Unlock()
+	archive.Entries[ArchiveKey(ID, format)] = entry
+	return entry, nil
+}
+
+// Valid checks whether the archived file still exists with the
+// recorded size
+func (entry *ArchiveEntry) Valid() bool {
+	info, err := os.Stat(entry.Path)
+	return err == nil && info.Size() == entry.Size
+}
+
+// checksumFile returns the size and SHA-256 of a file
+func checksumFile(path string) (int64, string, error) {
+	inFile, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer inFile.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, inFile)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// ArchiveCommand handles the archive subcommands
+func ArchiveCommand(opts docopt.Opts, config *Configuration) {
+	archive, err := LoadArchive()
+	if err != nil {
+		logrus.Fatalf("failed to load archive: %s", err)
+	}
+
+	if list, _ := opts.Bool("list"); list {
+		archiveList(archive)
+		return
+	}
+
+	if prune, _ := opts.Bool("prune"); prune {
+		removed := archive.Prune()
+		if err := archive.Save(); err != nil {
+			logrus.Fatalf("failed to save archive: %s", err)
+		}
+		fmt.Printf("Removed %d missing or changed files from the archive\n", removed)
+		return
+	}
+
+	if imp, _ := opts.Bool("import"); imp {
+		dir, err := opts.String("<dir>")
+		if err != nil {
+			logrus.Fatalf("failed to parse args: %s", err)
+		}
+		added, unrecognised, err := archive.Import(dir)
+		if err != nil {
+			logrus.Fatalf("failed to import %s: %s", dir, err)
+		}
+		if err := archive.Save(); err != nil {
+			logrus.Fatalf("failed to save archive: %s", err)
+		}
+		fmt.Printf("Imported %d files into the archive\n", added)
+		if unrecognised > 0 {
+			fmt.Printf("Skipped %d audio files without a %s tag. Files downloaded by older\n", unrecognised, songIDTag)
+			fmt.Printf("versions of deezerdl are not tagged and have to be downloaded again.\n")
+		}
+		return
+	}
+}
+
+// archiveList prints every entry in the archive
+func archiveList(archive *Archive) {
+	keys := make([]string, 0, len(archive.Entries))
+	for key := range archive.Entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		entry := archive.Entries[key]
+		fmt.Printf("%d\t%s\t%s\t%s\n", entry.ID, entry.Format, humanize.Bytes(uint64(entry.Size)), entry.Path)
+	}
+}
+
+// Prune removes entries whose files are missing or have changed size
+// and returns the number removed
+func (archive *Archive) Prune() int {
+	archive.mu.Lock()
+	defer archive.mu.Unlock()
+	removed := 0
+	for key, entry := range archive.Entries {
+		if !entry.Valid() {
+			delete(archive.Entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Import scans a directory for files downloaded by deezerdl, which
+// are recognised by their tags, and adds them to the archive. Returns
+// the number of files added and the number of audio files that were
+// not recognised. Versions of deezerdl before the archive did not tag
+// their files, so their downloads can't be recognised.
+func (archive *Archive) Import(dir string) (added, unrecognised int, err error) {
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".flac" && ext != ".mp3" {
+			return nil
+		}
+
+		fileTags, err := tags.ReadFile(path)
+		if err != nil {
+			logrus.Warnf("couldn't read tags from %s: %s", path, err)
+			unrecognised++
+			return nil
+		}
+		ID, err := strconv.Atoi(fileTags.Get(songIDTag))
+		if err != nil {
+			// not one of ours, or from an older version
+			unrecognised++
+			return nil
+		}
+		format, err := ParseFormat(fileTags.Get(formatTag))
+		if err != nil {
+			logrus.Warnf("unknown format in %s: %s", path, err)
+			return nil
+		}
+
+		if _, err := archive.Add(ID, format, path); err != nil {
+			return err
+		}
+		added++
+		return nil
+	})
+	return added, unrecognised, err
+}
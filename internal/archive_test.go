@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/joshbarrass/deezerdl/pkg/tags"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestFLAC writes a FLAC file with an empty STREAMINFO block and
+// the given tags
+func writeTestFLAC(t *testing.T, path string, fileTags tags.Tags) {
+	data := []byte("fLaC")
+	data = append(data, 0x80, 0, 0, 34)
+	data = append(data, make([]byte, 34)...)
+	data = append(data, 0xff, 0xf8, 0x00, 0x00)
+	assert.Equal(t, nil, ioutil.WriteFile(path, data, 0644))
+	assert.Equal(t, nil, tags.WriteFile(path, fileTags))
+}
+
+func TestArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deezerdl")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	archive := &Archive{
+		Entries: make(map[string]*ArchiveEntry),
+		path:    filepath.Join(dir, archiveFile),
+	}
+
+	ours := tags.Tags{}
+	ours.Set(songIDTag, "3135553")
+	ours.Set(formatTag, "FLAC")
+	writeTestFLAC(t, filepath.Join(dir, "ours.flac"), ours)
+	writeTestFLAC(t, filepath.Join(dir, "other.flac"), tags.Tags{"TITLE": {"Something Else"}})
+
+	t.Run("Import", func(t *testing.T) {
+		added, unrecognised, err := archive.Import(dir)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 1, added)
+		assert.Equal(t, 1, unrecognised)
+
+		entry, ok := archive.Lookup(3135553, deezer.FLAC)
+		assert.True(t, ok)
+		assert.Equal(t, 64, len(entry.Checksum))
+		_, ok = archive.Lookup(3135553, deezer.MP3_320)
+		assert.False(t, ok, "other formats should not match")
+	})
+
+	t.Run("Save", func(t *testing.T) {
+		assert.Equal(t, nil, archive.Save())
+		_, err := os.Stat(archive.path)
+		assert.Equal(t, nil, err)
+
+		// saving again replaces the file without leaving the
+		// temporary file behind
+		assert.Equal(t, nil, archive.Save())
+		matches, err := filepath.Glob(filepath.Join(dir, ".*"))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 0, len(matches))
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		assert.Equal(t, 0, archive.Prune())
+		assert.Equal(t, nil, os.Remove(filepath.Join(dir, "ours.flac")))
+		_, ok := archive.Lookup(3135553, deezer.FLAC)
+		assert.False(t, ok, "missing files should not be returned")
+		assert.Equal(t, 1, archive.Prune())
+		assert.Equal(t, 0, len(archive.Entries))
+	})
+}
@@ -0,0 +1,282 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Item types that can be downloaded
+const (
+	TrackItem    = "track"
+	AlbumItem    = "album"
+	PlaylistItem = "playlist"
+)
+
+// ArtistType is the type of artist links, which can't be downloaded
+// directly
+const ArtistType = "artist"
+
+var ErrBadItem = errors.New("expected a deezer URL or \"track|album|playlist <ID>\"")
+
+// Item is a single thing to download from a batch file
+type Item struct {
+	Type string
+	ID   int
+	// Format overrides the format for this item if not empty
+	Format string
+	// Line is the line of the batch file the item came from
+	Line int
+}
+
+func (item *Item) String() string {
+	return fmt.Sprintf("%s %d", item.Type, item.ID)
+}
+
+// isItemType checks whether s is a valid item type
+func isItemType(s string) bool {
+	return s == TrackItem || s == AlbumItem || s == PlaylistItem
+}
+
+// ParseItemURL parses a deezer URL such as
+// https://www.deezer.com/en/album/2795561
+func ParseItemURL(s string) (*Item, error) {
+	itemType, ID, err := parseDeezerURL(s)
+	if err != nil {
+		return nil, err
+	}
+	if !isItemType(itemType) {
+		return nil, ErrBadItem
+	}
+	return &Item{Type: itemType, ID: ID}, nil
+}
+
+// parseDeezerURL gets the type and ID of whatever a deezer URL links
+// to, which need not be something that can be downloaded
+func parseDeezerURL(s string) (string, int, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", 0, err
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != "deezer.com" && !strings.HasSuffix(host, ".deezer.com") {
+		return "", 0, ErrBadItem
+	}
+
+	// the path may start with a language code
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if isItemType(parts[i]) || parts[i] == ArtistType {
+			ID, err := strconv.Atoi(parts[i+1])
+			if err != nil {
+				return "", 0, ErrBadItem
+			}
+			return parts[i], ID, nil
+		}
+	}
+	return "", 0, ErrBadItem
+}
+
+// ParseItem parses a single line of a batch file. Lines are either
+// "<URL> [format]" or "<type> <ID> [format]". Returns nil for blank
+// lines and comments, which start with #.
+func ParseItem(line string) (*Item, error) {
+	// comments start at a # at the start of the line or after
+	// whitespace, so that URLs with fragments are left alone
+	for i, r := range line {
+		if r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			line = line[:i]
+			break
+		}
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	var item *Item
+	var rest []string
+	if isItemType(fields[0]) {
+		if len(fields) < 2 {
+			return nil, ErrBadItem
+		}
+		ID, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, ErrBadItem
+		}
+		item = &Item{Type: fields[0], ID: ID}
+		rest = fields[2:]
+	} else {
+		var err error
+		item, err = ParseItemURL(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		rest = fields[1:]
+	}
+
+	switch len(rest) {
+	case 0:
+	case 1:
+		if _, err := ParseFormat(rest[0]); err != nil {
+			return nil, err
+		}
+		item.Format = rest[0]
+	default:
+		return nil, fmt.Errorf("unexpected text after item: %s", strings.Join(rest[1:], " "))
+	}
+	return item, nil
+}
+
+// ReadItems reads a batch file, skipping blank lines and comments. All
+// lines are checked before any are returned, so a typo does not
+// abort a batch half-way through.
+func ReadItems(r io.Reader) ([]*Item, error) {
+	var items []*Item
+	var errs []string
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		item, err := ParseItem(scanner.Text())
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: %s", lineNumber, err))
+			continue
+		}
+		if item == nil {
+			continue
+		}
+		item.Line = lineNumber
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	return items, nil
+}
+
+// ReadItemsFile reads a batch file from a path, or from stdin if the
+// path is "-"
+func ReadItemsFile(path string) ([]*Item, error) {
+	if path == "-" {
+		return ReadItems(os.Stdin)
+	}
+	inFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer inFile.Close()
+	return ReadItems(inFile)
+}
+
+// downloadBatch downloads every item using the same logged in API,
+// carrying on past failures, and prints a summary at the end. Returns
+// false if any item failed.
+func (dl *downloader) downloadBatch(items []*Item) bool {
+	errs := make([]error, len(items))
+	for i, item := range items {
+		dl.out.Printf("\n[%d/%d] %s\n", i+1, len(items), item)
+		errs[i] = dl.downloadItem(item)
+		if errs[i] != nil {
+			dl.out.Printf("Failed to download %s: %s\n", item, errs[i])
+		}
+	}
+
+	// summary
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	dl.out.Println("\nSummary:")
+	dl.out.Printf("  Items: %d succeeded, %d failed\n", len(items)-failed, failed)
+	if dl.plan != nil {
+		dl.plan.printTotals(dl.out)
+	} else {
+		dl.out.Printf("  Tracks: %d downloaded, %d skipped\n", dl.stats.Downloaded, dl.stats.Skipped)
+	}
+	for i, err := range errs {
+		if err != nil {
+			dl.out.Printf("  Line %d (%s): %s\n", items[i].Line, items[i], err)
+		}
+	}
+	dl.emitSummary(items, errs)
+	return failed == 0
+}
+
+// emitSummary emits the final JSON summary event for a run
+func (dl *downloader) emitSummary(items []*Item, errs []error) {
+	failed := 0
+	var failures []Event
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			failures = append(failures, Event{
+				"type":    items[i].Type,
+				"id":      items[i].ID,
+				"line":    items[i].Line,
+				"message": err.Error(),
+			})
+		}
+	}
+	summary := Event{
+		"items_succeeded":   len(items) - failed,
+		"items_failed":      failed,
+		"tracks_downloaded": dl.stats.Downloaded,
+		"tracks_skipped":    dl.stats.Skipped,
+		"failures":          failures,
+	}
+	if plan := dl.plan; plan != nil {
+		plan.mu.Lock()
+		summary["dry_run"] = true
+		summary["tracks_planned"] = plan.Downloads
+		summary["tracks_skipped"] = plan.Skips
+		summary["bytes_planned"] = plan.Bytes
+		summary["conflicts"] = plan.Conflicts
+		plan.mu.Unlock()
+	}
+	dl.out.Event(SummaryEvent, summary)
+}
+
+// downloadItem downloads a single item, using its own format if it
+// has one
+func (dl *downloader) downloadItem(item *Item) error {
+	itemDL := dl
+	if item.Format != "" {
+		format, err := ParseFormat(item.Format)
+		if err != nil {
+			return err
+		}
+		copied := *dl
+		copied.format = format
+		itemDL = &copied
+	}
+
+	var err error
+	switch item.Type {
+	case TrackItem:
+		err = itemDL.downloadTrack(item.ID)
+	case AlbumItem:
+		err = itemDL.downloadAlbum(item.ID)
+	case PlaylistItem:
+		err = itemDL.downloadPlaylist(item.ID)
+	default:
+		err = ErrBadItem
+	}
+	if err != nil {
+		dl.out.Event(ErrorEvent, Event{
+			"type":    item.Type,
+			"id":      item.ID,
+			"message": err.Error(),
+		})
+	}
+	return err
+}
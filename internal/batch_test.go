@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseItem(t *testing.T) {
+	cases := map[string]*Item{
+		"track 3135553":                                     {Type: TrackItem, ID: 3135553},
+		"album 2795561 FLAC":                                {Type: AlbumItem, ID: 2795561, Format: "FLAC"},
+		"https://www.deezer.com/en/album/2795561":           {Type: AlbumItem, ID: 2795561},
+		"https://www.deezer.com/playlist/908622995 MP3_320": {Type: PlaylistItem, ID: 908622995, Format: "MP3_320"},
+		"https://deezer.com/track/3135553?utm=x # comment":  {Type: TrackItem, ID: 3135553},
+	}
+	for line, expected := range cases {
+		item, err := ParseItem(line)
+		assert.Equal(t, nil, err, line)
+		assert.Equal(t, expected, item, line)
+	}
+
+	for _, line := range []string{"", "   ", "# just a comment"} {
+		item, err := ParseItem(line)
+		assert.Equal(t, nil, err, line)
+		assert.Nil(t, item, line)
+	}
+
+	for _, line := range []string{"3135553", "track", "track abc", "album 1 WAV", "https://example.com/track/1", "https://evildeezer.com/track/1", "https://deezer.com.example.com/track/1", "track 1 FLAC extra"} {
+		_, err := ParseItem(line)
+		assert.NotEqual(t, nil, err, line)
+	}
+}
+
+func TestReadItems(t *testing.T) {
+	input := "# my queue\ntrack 1\n\nalbum 2 FLAC\n"
+	items, err := ReadItems(strings.NewReader(input))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []*Item{
+		{Type: TrackItem, ID: 1, Line: 2},
+		{Type: AlbumItem, ID: 2, Format: "FLAC", Line: 4},
+	}, items)
+
+	_, err = ReadItems(strings.NewReader("track 1\nnonsense\ntrack x\n"))
+	assert.Contains(t, err.Error(), "line 2")
+	assert.Contains(t, err.Error(), "line 3")
+}
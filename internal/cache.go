@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docopt/docopt-go"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/joshbarrass/deezerdl/pkg/cache"
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/sirupsen/logrus"
+)
+
+// GetCacheDir returns the platform-specific cache directory, e.g.
+// $XDG_CACHE_HOME/deezerdl
+func GetCacheDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userCacheDir, configDirSuffix), nil
+}
+
+// SkipCache turns off the metadata cache for this run only
+func (config *Configuration) SkipCache() {
+	config.skipCache = true
+}
+
+// NewCache opens the metadata cache of the current profile with the
+// size limit from the config
+func (config *Configuration) NewCache() (*cache.DiskCache, error) {
+	dir, err := GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	diskCache := cache.NewDiskCache(os.ExpandEnv(dir))
+	diskCache.Profile = config.ProfileName()
+	if config.CacheMaxSize != "" {
+		size, err := humanize.ParseBytes(config.CacheMaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache size %q: %s", config.CacheMaxSize, err)
+		}
+		diskCache.MaxSize = int64(size)
+	}
+	return diskCache, nil
+}
+
+// useCache configures the api with the metadata cache, unless it has
+// been turned off. The cache is only an optimisation, so failing to
+// open it is not fatal.
+func (config *Configuration) useCache(api *deezer.API) {
+	if config.NoCache || config.skipCache {
+		return
+	}
+	diskCache, err := config.NewCache()
+	if err != nil {
+		logrus.Warnf("failed to open cache: %s", err)
+		return
+	}
+	api.SetCache(diskCache)
+}
+
+// CacheCommand handles the cache subcommands
+func CacheCommand(opts docopt.Opts, config *Configuration) {
+	diskCache, err := config.NewCache()
+	if err != nil {
+		logrus.Fatalf("failed to open cache: %s", err)
+	}
+
+	if stats, _ := opts.Bool("stats"); stats {
+		cacheStats(diskCache)
+		return
+	}
+
+	if clearCache, _ := opts.Bool("clear"); clearCache {
+		if err := diskCache.Clear(); err != nil {
+			logrus.Fatalf("failed to clear cache: %s", err)
+		}
+		fmt.Println("Cleared the cache")
+		return
+	}
+}
+
+// cacheStats prints the number and size of the entries for each
+// method in the cache
+func cacheStats(diskCache *cache.DiskCache) {
+	stats, err := diskCache.Stats()
+	if err != nil {
+		logrus.Fatalf("failed to read cache: %s", err)
+	}
+	fmt.Printf("Cache: %s\n", diskCache.Dir)
+	for _, method := range stats.Methods {
+		fmt.Printf("  %-14s %5d entries (%d expired), %s\n", method.Method, method.Entries, method.Expired, humanize.IBytes(uint64(method.Size)))
+	}
+	fmt.Printf("Total: %d entries, %s of %s\n", stats.Entries, humanize.IBytes(uint64(stats.Size)), humanize.IBytes(uint64(diskCache.MaxSize)))
+}
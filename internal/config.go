@@ -5,25 +5,57 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/sirupsen/logrus"
 )
 
 const (
 	configDirSuffix             = "deezerdl"
 	configDirPerms  os.FileMode = 0755
 	configFile                  = "config.json"
+	configFilePerms os.FileMode = 0644
 )
 
+const configVersion = "2"
+
 type Configuration struct {
-	Version       string `json:"version"`
-	ARLCookie     string `json:"arl"`
-	DefaultFormat string `json:"default_format"`
+	Version       string              `json:"version"`
+	ActiveProfile string              `json:"active_profile"`
+	Profiles      map[string]*Profile `json:"profiles"`
+	SecretStore   string              `json:"secret_store"`
+	// NoCache turns off the metadata cache, and CacheMaxSize limits
+	// its size, e.g. "100MB"
+	NoCache      bool   `json:"no_cache,omitempty"`
+	CacheMaxSize string `json:"cache_max_size,omitempty"`
+	// RateLimits is the number of requests per second allowed to
+	// each endpoint, overriding DefaultRateLimits. 0 means no limit.
+	RateLimits map[deezer.Endpoint]float64 `json:"rate_limits,omitempty"`
+	// LimitRate is the limit on the total download speed, e.g.
+	// "2MiB/s"
+	LimitRate string `json:"limit_rate,omitempty"`
+
+	// ARLCookie and DefaultFormat are only read so that configs from
+	// older versions can be migrated into the default profile
+	ARLCookie     string `json:"arl,omitempty"`
+	DefaultFormat string `json:"default_format,omitempty"`
+
+	// profileOverride is the profile selected with --profile for
+	// this run only
+	profileOverride string
+	// skipCache is set by --no-cache for this run only
+	skipCache bool
 }
 
 // NewConfiguration creates an empty, default config
 func NewConfiguration() *Configuration {
 	return &Configuration{
-		Version:       "1",
-		DefaultFormat: "MP3_320",
+		Version:       configVersion,
+		ActiveProfile: DefaultProfileName,
+		Profiles: map[string]*Profile{
+			DefaultProfileName: NewProfile(),
+		},
+		SecretStore: FileSecretStoreName,
 	}
 }
 
@@ -48,7 +80,6 @@ func CreateConfig() error {
 	if err != nil {
 		return err
 	}
-	fmt.Println(configDir)
 
 	// check if config dir exists
 	if exists, err := FileExists(os.ExpandEnv(configDir)); err != nil {
@@ -99,9 +130,110 @@ func LoadConfig() (*Configuration, error) {
 	if err := decoder.Decode(&config); err != nil {
 		return nil, err
 	}
+
+	if err := config.migrate(); err != nil {
+		return nil, err
+	}
 	return &config, nil
 }
 
+// migrate updates a config from an older version. Version 1 configs
+// kept a single default format at the top level, and older still
+// stored the ARL cookie in plaintext.
+func (config *Configuration) migrate() error {
+	changed := false
+
+	if len(config.Profiles) == 0 {
+		profile := NewProfile()
+		if config.DefaultFormat != "" {
+			profile.DefaultFormat = config.DefaultFormat
+		}
+		config.Profiles = map[string]*Profile{
+			DefaultProfileName: profile,
+		}
+		config.ActiveProfile = DefaultProfileName
+		config.DefaultFormat = ""
+		changed = true
+	}
+
+	if config.ARLCookie != "" {
+		migrated, err := config.migrateARL()
+		if err != nil {
+			return fmt.Errorf("failed to migrate arl to the secret store: %s", err)
+		}
+		if migrated {
+			config.ARLCookie = ""
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	config.Version = configVersion
+	return config.SaveConfig()
+}
+
+// migrateARL moves the plaintext arl cookie into the secret store.
+// The env secret store can't be written to, so the cookie is only
+// dropped from the config once the environment variable is set, and
+// is used until then.
+func (config *Configuration) migrateARL() (bool, error) {
+	err := config.SetARL(config.ARLCookie)
+	if err != ErrReadOnlySecrets {
+		return err == nil, err
+	}
+
+	store, err := config.Secrets()
+	if err != nil {
+		return false, err
+	}
+	name := arlSecretName(config.ProfileName())
+	if _, err := store.Get(name); err == nil {
+		return true, nil
+	}
+	variable := name
+	if envStore, ok := store.(*EnvSecretStore); ok {
+		variable = envStore.VariableName(name)
+	}
+	logrus.Warnf("the arl cookie is still stored in plaintext in the config; export %s to move it out", variable)
+	return false, nil
+}
+
+// Secrets returns the secret store selected in the config
+func (config *Configuration) Secrets() (SecretStore, error) {
+	return NewSecretStore(config.SecretStore)
+}
+
+// GetARL reads the ARL cookie of the current profile from the secret
+// store
+func (config *Configuration) GetARL() (string, error) {
+	store, err := config.Secrets()
+	if err != nil {
+		return "", err
+	}
+	name := config.ProfileName()
+	arl, err := store.Get(arlSecretName(name))
+	if err == ErrSecretNotFound && config.ARLCookie != "" {
+		// not migrated yet
+		return config.ARLCookie, nil
+	}
+	if err == ErrSecretNotFound {
+		return "", fmt.Errorf("no arl cookie found for profile %s -- run deezerdl login first", name)
+	}
+	return arl, err
+}
+
+// SetARL saves the ARL cookie of the current profile to the secret
+// store
+func (config *Configuration) SetARL(arl string) error {
+	store, err := config.Secrets()
+	if err != nil {
+		return err
+	}
+	return store.Set(arlSecretName(config.ProfileName()), arl)
+}
+
 // SaveConfig saves the config object. The configuration file must
 // already exist -- call CreateConfig first if this is not the case.
 func (config *Configuration) SaveConfig() error {
@@ -111,7 +243,7 @@ func (config *Configuration) SaveConfig() error {
 	}
 	// open config
 	fullPath := filepath.Join(os.ExpandEnv(configDir), configFile)
-	outFile, err := os.Create(fullPath)
+	outFile, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, configFilePerms)
 	if err != nil {
 		return err
 	}
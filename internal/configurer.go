@@ -2,8 +2,10 @@ package internal
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/docopt/docopt-go"
+	humanize "github.com/dustin/go-humanize"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,21 +22,86 @@ func Configure(opts docopt.Opts, config *Configuration) {
 }
 
 func configureSet(opts docopt.Opts, config *Configuration) {
-	// set default format
-	if _, ok := opts["DefaultFormat"]; ok {
-		if selection, err := opts.Bool("DefaultFormat"); err != nil {
-			logrus.Fatalf("failed to parse args: %s", err)
-		} else if selection {
-			var format string
-			format, err = opts.String("<fmt>")
-			if err != nil {
-				logrus.Fatalf("failed to parse args: %s", err)
-			}
-			FormatStringToFormat(format)
-			config.DefaultFormat = format
-			config.SaveConfig()
-			fmt.Printf("Set default format to %s\n", format)
-			return
+	key, err := opts.String("<key>")
+	if err != nil {
+		logrus.Fatalf("failed to parse args: %s", err)
+	}
+	value, err := opts.String("<value>")
+	if err != nil {
+		logrus.Fatalf("failed to parse args: %s", err)
+	}
+
+	profile, err := config.Profile()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	switch key {
+	// profile settings
+	case "DefaultFormat":
+		FormatStringToFormat(value)
+		profile.DefaultFormat = value
+	case "OutputDir":
+		profile.OutputDir = value
+	case "TrackTemplate":
+		if _, err := RenderTemplate(value, &TemplateData{}); err != nil {
+			logrus.Fatalf("invalid template: %s", err)
+		}
+		profile.TrackTemplate = value
+	case "AlbumTemplate":
+		if _, err := RenderTemplate(value, &TemplateData{}); err != nil {
+			logrus.Fatalf("invalid template: %s", err)
+		}
+		profile.AlbumTemplate = value
+	case "DiscLayout":
+		if err := checkDiscLayout(value); err != nil {
+			logrus.Fatal(err)
+		}
+		profile.DiscLayout = value
+	case "ArtistSeparator":
+		profile.ArtistSeparator = value
+	case "LyricsFile", "EmbedLyrics":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			logrus.Fatalf("invalid value for %s: %s", key, value)
 		}
+		if key == "LyricsFile" {
+			profile.LyricsFile = enabled
+		} else {
+			profile.EmbedLyrics = enabled
+		}
+
+	// global settings
+	case "SecretStore":
+		if _, err := NewSecretStore(value); err != nil {
+			logrus.Fatalf("invalid secret store: %s", err)
+		}
+		config.SecretStore = value
+	case "Cache":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			logrus.Fatalf("invalid value for Cache: %s", value)
+		}
+		config.NoCache = !enabled
+	case "CacheMaxSize":
+		if _, err := humanize.ParseBytes(value); err != nil {
+			logrus.Fatalf("invalid cache size: %s", err)
+		}
+		config.CacheMaxSize = value
+	case "LimitRate":
+		if _, err := ParseByteRate(value); err != nil {
+			logrus.Fatal(err)
+		}
+		config.LimitRate = value
+	case "GatewayRateLimit", "PublicRateLimit", "CDNRateLimit":
+		if err := config.setRateLimit(rateLimitKeys[key], value); err != nil {
+			logrus.Fatal(err)
+		}
+	default:
+		logrus.Fatalf("unknown config key: %s", key)
+	}
+
+	if err := config.SaveConfig(); err != nil {
+		logrus.Fatalf("failed to save config: %s", err)
 	}
+	fmt.Printf("Set %s to %s\n", key, value)
 }
@@ -4,66 +4,153 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/docopt/docopt-go"
 	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/joshbarrass/deezerdl/pkg/ratelimit"
+	"github.com/joshbarrass/deezerdl/pkg/tags"
+	"github.com/joshbarrass/deezerdl/pkg/verify"
 	"github.com/joshbarrass/deezerdl/pkg/writetracker"
 	"github.com/sirupsen/logrus"
 )
 
+// downloadAttempts is the number of times a track is downloaded
+// before giving up if it keeps failing verification
+const downloadAttempts = 3
+
 // https://progolang.com/how-to-download-files-in-go/
 
-func DownloadFile(url, outPath string) error {
+// DownloadFile downloads url from the CDN to outPath, tracking the
+// progress with tracker and reading no faster than limiter allows. The
+// tracker's total is replaced by the Content-Length if the server
+// sends one. The tracker is not finished, as the caller may still
+// reject the file.
+func DownloadFile(api *deezer.API, url, outPath string, tracker *writetracker.WriteTracker, limiter *ratelimit.Limiter) error {
 	// Get the file
-	resp, err := http.Get(url)
+	resp, err := api.CDNRequest(url)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		return errors.New(fmt.Sprintf("bad status code: %d", resp.StatusCode))
 	}
-	defer resp.Body.Close()
 
 	// make the file on disk to be written to
-	outFile, err := os.Create(outPath + ".part")
+	partPath := outPath + ".part"
+	outFile, err := os.Create(partPath)
 	if err != nil {
 		return err
 	}
+	defer outFile.Close()
+
+	if resp.ContentLength > 0 {
+		tracker.Total = uint64(resp.ContentLength)
+	}
 
 	// write to the file
-	wt := writetracker.NewWriteTracker("")
-	_, err = io.Copy(outFile, io.TeeReader(resp.Body, wt))
+	_, err = io.Copy(outFile, io.TeeReader(ratelimit.NewReader(resp.Body, limiter), tracker))
+	if err == nil {
+		err = outFile.Close()
+	}
 	if err != nil {
+		os.Remove(partPath)
 		return err
 	}
-	outFile.Close()
-
-	// move to new line because of how ShowProgress works
-	fmt.Println("")
 
 	// rename part file
-	err = os.Rename(outPath+".part", outPath)
+	err = os.Rename(partPath, outPath)
 	if err != nil {
+		os.Remove(partPath)
 		return err
 	}
 
 	return nil
 }
 
+// downloadStats counts the tracks handled in one run
+type downloadStats struct {
+	Downloaded int
+	Skipped    int
+	mu         sync.Mutex
+}
+
+// add increments the counters
+func (stats *downloadStats) add(downloaded, skipped int) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.Downloaded += downloaded
+	stats.Skipped += skipped
+}
+
+// trackJob is a single track to be downloaded to a path
+type trackJob struct {
+	Track  *deezer.Track
+	Album  *deezer.Album
+	Number int
+	// Disc and TotalDiscs are only set for album tracks
+	Disc       int
+	TotalDiscs int
+	Path       string
+}
+
+// downloader holds everything needed to download tracks for one run
+type downloader struct {
+	api     *deezer.API
+	profile *Profile
+	format  deezer.Format
+	archive *Archive
+	force   bool
+	stats   *downloadStats
+	out     *Output
+	// jobs is the number of tracks downloaded at once
+	jobs int
+	// progress draws the progress bars, or is nil in JSON mode
+	progress *writetracker.Manager
+	// plan collects what would be downloaded, and is only set for a
+	// dry run
+	plan *dryRunPlan
+	// bandwidth limits the total download speed of all jobs, or is
+	// nil for no limit
+	bandwidth *ratelimit.Limiter
+}
+
 // Download reads arguments from docopt options to work out what to
 // download
 func Download(opts docopt.Opts, config *Configuration) {
-	// get the ID
-	if _, ok := opts["<ID>"]; !ok {
-		logrus.Fatal("missing ID")
+	// get the items, either from the arguments or from a batch file
+	var items []*Item
+	if fromFile, err := opts.String("--from-file"); err == nil && fromFile != "" {
+		items, err = ReadItemsFile(fromFile)
+		if err != nil {
+			logrus.Fatalf("failed to read %s: %s", fromFile, err)
+		}
+	} else {
+		ID, err := opts.Int("<ID>")
+		if err != nil {
+			logrus.Fatalf("failed to parse arguments: %s", err)
+		}
+		for _, itemType := range []string{TrackItem, AlbumItem, PlaylistItem} {
+			if selected, _ := opts.Bool(itemType); selected {
+				items = append(items, &Item{Type: itemType, ID: ID})
+			}
+		}
 	}
-	ID, err := opts.Int("<ID>")
+
+	jsonMode, _ := opts.Bool("--json")
+	out := NewOutput(jsonMode)
+
+	profile, err := config.Profile()
 	if err != nil {
-		logrus.Fatalf("failed to parse arguments: %s", err)
+		logrus.Fatal(err)
 	}
 
 	// get format
@@ -75,137 +162,570 @@ func Download(opts docopt.Opts, config *Configuration) {
 	}
 	if !ok || err != nil || formatString == "" {
 		// does not exist or failed, use config
-		if config.DefaultFormat != "" {
-			formatString = config.DefaultFormat
+		if profile.DefaultFormat != "" {
+			formatString = profile.DefaultFormat
 			err = nil
-			fmt.Printf("Using format from config: %s\n", formatString)
+			out.Printf("Using format from config: %s\n", formatString)
 		} else {
 			logrus.Fatal("no format specified and no format in your config")
 		}
 	} else {
-		fmt.Printf("Using format: %s\n", formatString)
+		out.Printf("Using format: %s\n", formatString)
 	}
 	if err != nil {
 		logrus.Fatalf("failed to get format: %s", err)
 	}
 	format := FormatStringToFormat(formatString)
 
-	// make API
-	api, err := deezer.NewAPI(false)
+	force, _ := opts.Bool("--force")
+	dryRun, _ := opts.Bool("--dry-run")
+
+	jobs := 1
+	if jobsString, err := opts.String("--jobs"); err == nil && jobsString != "" {
+		jobs, err = strconv.Atoi(jobsString)
+		if err != nil || jobs < 1 {
+			logrus.Fatalf("invalid number of jobs: %s", jobsString)
+		}
+	}
+
+	limitRate := config.LimitRate
+	if limitString, err := opts.String("--limit-rate"); err == nil && limitString != "" {
+		limitRate = limitString
+	}
+	rate, err := ParseByteRate(limitRate)
 	if err != nil {
-		logrus.Fatalf("failed to create api: %s", err)
+		logrus.Fatal(err)
 	}
 
-	// log in
-	if err := api.CookieLogin(config.ARLCookie); err != nil {
+	archive, err := LoadArchive()
+	if err != nil {
+		logrus.Fatalf("failed to load archive: %s", err)
+	}
+
+	// make API and log in
+	api, err := NewLoggedInAPI(config)
+	if err != nil {
+		out.Event(ErrorEvent, Event{"message": err.Error()})
 		logrus.Fatalf("failed to log in: %s", err)
 	}
 
-	// check track
-	if track, err := opts.Bool("track"); err != nil {
-		logrus.Fatalf("failed to parse arguments: %s", err)
-	} else if track {
-		if err := downloadTrack(format, ID, api); err != nil {
-			logrus.Fatalf("failed to download track: %s", err)
-			return
-		}
+	dl := &downloader{
+		api:       api,
+		profile:   profile,
+		format:    format,
+		archive:   archive,
+		force:     force,
+		stats:     &downloadStats{},
+		out:       out,
+		jobs:      jobs,
+		bandwidth: newBandwidthLimiter(rate),
+	}
+	if dryRun {
+		dl.plan = newDryRunPlan()
+	} else if !out.JSON {
+		dl.progress = writetracker.NewManager(os.Stdout)
+		out.progress = dl.progress
 	}
 
-	// check album
-	if album, err := opts.Bool("album"); err != nil {
-		logrus.Fatalf("failed to parse arguments: %s", err)
-	} else if album {
-		if err := downloadAlbum(format, ID, api); err != nil {
-			logrus.Fatalf("failed to download album: %s", err)
-			return
+	// a single item fails straight away, but a batch carries on and
+	// reports at the end
+	if len(items) == 1 && items[0].Line == 0 {
+		err := dl.downloadItem(items[0])
+		dl.emitSummary(items, []error{err})
+		if err != nil {
+			logrus.Fatalf("failed to download %s: %s", items[0].Type, err)
+		}
+		if dl.plan != nil {
+			dl.out.Println("\nDry run, nothing was downloaded:")
+			dl.plan.printTotals(dl.out)
 		}
+		return
+	}
+	if !dl.downloadBatch(items) {
+		os.Exit(1)
 	}
 }
 
 // downloadTrack is for downloading an individual track
-func downloadTrack(format deezer.Format, ID int, api *deezer.API) error {
+func (dl *downloader) downloadTrack(ID int) error {
 	// get track info
-	fmt.Println("\nGetting track info...")
-	track, err := api.GetSongData(ID)
+	dl.out.Println("\nGetting track info...")
+	track, err := dl.api.GetSongData(ID)
+	if err != nil {
+		return err
+	}
+	dl.out.Println("Got track info")
+	dl.out.Println("")
+	dl.out.Event(ResolvedEvent, Event{
+		"type":  TrackItem,
+		"id":    track.ID,
+		"title": track.Title,
+	})
+
+	filename, err := CalculateFilename(dl.profile.TrackTemplate, NewTemplateData(track, nil, 0, dl.format), dl.format)
 	if err != nil {
 		return err
 	}
-	fmt.Println("Got track info")
-	fmt.Println("")
 
-	// get the download URL
-	downloadUrl, err := track.GetDownloadURL(format)
+	job := trackJob{
+		Track: track,
+		Path:  filepath.Join(dl.profile.OutputDir, filename),
+	}
+	if dl.plan != nil {
+		return dl.planJobs("", []trackJob{job})
+	}
+
+	// make sure the output dir exists
+	if err := os.MkdirAll(dl.profile.OutputDir, configDirPerms); err != nil {
+		return err
+	}
+
+	return dl.downloadTrackTo(job)
+}
+
+// downloadJobs downloads a list of tracks, several at once if
+// configured to. All tracks are attempted, and the first error is
+// returned.
+func (dl *downloader) downloadJobs(title string, jobs []trackJob) error {
+	if dl.plan != nil {
+		return dl.planJobs(title, jobs)
+	}
+	if dl.progress != nil {
+		dl.progress.SetOverall(title, len(jobs))
+		defer dl.progress.SetOverall("", 0)
+	}
+
+	return dl.eachJob(jobs, dl.downloadTrackTo)
+}
+
+// eachJob calls download for every job, dl.jobs at a time. Failures
+// are reported and the other jobs carry on, whether or not they run
+// in parallel. The first error is returned.
+func (dl *downloader) eachJob(jobs []trackJob, download func(trackJob) error) error {
+	var mu sync.Mutex
+	var firstErr error
+	run := func(job trackJob) {
+		if err := download(job); err != nil {
+			dl.out.Printf("Failed to download %s: %s\n", job.Track.Title, err)
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+		}
+	}
+
+	if dl.jobs <= 1 {
+		for _, job := range jobs {
+			run(job)
+		}
+		return firstErr
+	}
+
+	var wg sync.WaitGroup
+	queue := make(chan trackJob)
+	for i := 0; i < dl.jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				run(job)
+			}
+		}()
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	return firstErr
+}
+
+// downloadTrackTo downloads, decrypts, verifies and tags a track,
+// saving it to the given path. Tracks already in the archive are
+// skipped unless forced.
+func (dl *downloader) downloadTrackTo(job trackJob) error {
+	track, outPath := job.Track, job.Path
+	if entry, ok := dl.archive.Lookup(track.ID, dl.format); ok && !dl.force {
+		dl.out.Printf("Skipping %s -- already downloaded to %s\n", track.Title, entry.Path)
+		dl.out.Event(TrackSkippedEvent, Event{
+			"id":     track.ID,
+			"title":  track.Title,
+			"format": FormatToFormatString(dl.format),
+			"path":   entry.Path,
+		})
+		dl.stats.add(0, 1)
+		if dl.progress != nil {
+			dl.progress.Skip()
+		}
+		return nil
+	}
+
+	// get the download URL. If it can't be made or downloaded from,
+	// the cached song data may be out of date.
+	downloadUrl, err := track.GetDownloadURL(dl.format)
 	if err != nil {
+		track.Uncache()
 		return err
 	}
 
-	filename := CalculateFilename(track, format)
-	fmt.Printf("Downloading %s\n", filename)
-	fmt.Println("")
+	dl.out.Event(TrackStartedEvent, Event{
+		"id":     track.ID,
+		"title":  track.Title,
+		"format": FormatToFormatString(dl.format),
+		"path":   outPath,
+	})
+
+	// a download that fails verification is most likely corrupt, so
+	// try it again a few times before giving up. The progress is only
+	// counted as complete once the track has passed.
+	for attempt := 1; ; attempt++ {
+		tracker, discard := dl.newTracker(track)
+		if err := dl.fetchTrack(job, downloadUrl.String(), tracker); err != nil {
+			discard()
+			track.Uncache()
+			return err
+		}
+		err := dl.verifyTrack(job)
+		if err == nil {
+			tracker.Finish()
+			break
+		}
+		discard()
+		os.Remove(outPath)
+		if attempt >= downloadAttempts {
+			return fmt.Errorf("%s failed verification: %s", filepath.Base(outPath), err)
+		}
+		dl.out.Printf("%s failed verification (%s), retrying...\n", filepath.Base(outPath), err)
+		dl.out.Event(TrackRetryEvent, Event{
+			"id":      track.ID,
+			"title":   track.Title,
+			"attempt": attempt,
+			"error":   err.Error(),
+		})
+	}
+
+	trackTags := dl.trackTags(job)
+	dl.saveLyrics(job, trackTags)
+	if err := tags.WriteFile(outPath, trackTags); err != nil {
+		logrus.Warnf("couldn't tag %s: %s", outPath, err)
+	}
+
+	// remember the download. The track itself is fine even if this
+	// fails, so it is only a warning.
+	finished := Event{
+		"id":     track.ID,
+		"title":  track.Title,
+		"format": FormatToFormatString(dl.format),
+		"path":   outPath,
+	}
+	if entry, err := dl.archive.Add(track.ID, dl.format, outPath); err != nil {
+		logrus.Warnf("couldn't add %s to the archive: %s", outPath, err)
+	} else {
+		finished["size"] = entry.Size
+		finished["sha256"] = entry.Checksum
+		if err := dl.archive.Save(); err != nil {
+			logrus.Warnf("couldn't save the archive: %s", err)
+		}
+	}
+	dl.out.Event(TrackFinishedEvent, finished)
+
+	dl.stats.add(1, 0)
+	dl.out.Printf("Done: %s\n", filepath.Base(outPath))
+	return nil
+}
+
+// newTracker creates the tracker for one attempt at downloading a
+// track. discard drops its progress bar without counting it as
+// complete.
+func (dl *downloader) newTracker(track *deezer.Track) (tracker *writetracker.WriteTracker, discard func()) {
+	size := uint64(track.FileSize(dl.format))
+	if dl.out.JSON {
+		tracker = writetracker.NewWriteTracker(size, &jsonReporter{out: dl.out, ID: track.ID})
+		tracker.Interval = progressEventInterval
+		return tracker, func() {}
+	}
+	bar := dl.progress.Add(track.Title)
+	tracker = writetracker.NewWriteTracker(size, bar)
+	tracker.Interval = dl.progress.Interval
+	return tracker, bar.Remove
+}
 
-	encFilename := filename + ".enc"
+// fetchTrack downloads and decrypts a single track to its output path,
+// reporting the download's progress to tracker
+func (dl *downloader) fetchTrack(job trackJob, downloadUrl string, tracker *writetracker.WriteTracker) error {
+	track, outPath := job.Track, job.Path
+	dl.out.Printf("Downloading %s\n", filepath.Base(outPath))
+	encFilename := outPath + ".enc"
 
 	// download file
-	if err := DownloadFile(downloadUrl.String(), encFilename); err != nil {
+	if err := DownloadFile(dl.api, downloadUrl, encFilename, tracker, dl.bandwidth); err != nil {
 		return err
 	}
 	defer os.Remove(encFilename)
 
 	// decrypt song
-	fmt.Println("Decrypting...")
-	fmt.Println("")
+	dl.out.Printf("Decrypting %s...\n", filepath.Base(outPath))
 	key := track.GetBlowfishKey()
-	err = deezer.DecryptSongFile(key, encFilename, filename)
-	if err != nil {
+	return deezer.DecryptSongFile(key, encFilename, outPath)
+}
+
+// verifyTrack checks that a decrypted track is the size Deezer says it
+// should be and is a complete, valid audio file. It must run before
+// the file is tagged, as tagging changes the size.
+func (dl *downloader) verifyTrack(job trackJob) error {
+	if err := verify.Size(job.Path, job.Track.FileSize(dl.format)); err != nil {
 		return err
 	}
+	if dl.format == deezer.FLAC {
+		return verify.FLACFile(job.Path)
+	}
+	return verify.MP3File(job.Path)
+}
 
-	fmt.Println("Done!")
-	return nil
+// creditTags lists the tags that each contributor role is written to
+var creditTags = []struct {
+	Role deezer.ContributorRole
+	Tag  string
+}{
+	{deezer.RoleComposer, "COMPOSER"},
+	{deezer.RoleAuthor, "LYRICIST"},
+	{deezer.RoleProducer, "PRODUCER"},
+	{deezer.RoleMixer, "MIXER"},
+	{deezer.RoleEngineer, "ENGINEER"},
+	{deezer.RoleFeaturing, "PERFORMER"},
+}
+
+// trackTags builds the tags written to a downloaded track
+func (dl *downloader) trackTags(job trackJob) tags.Tags {
+	t := tags.Tags{}
+	t.Set("TITLE", job.Track.Title)
+	t.Set("ARTIST", dl.profile.joinArtists(job.Track.ArtistNames())...)
+	for _, credit := range creditTags {
+		t.Set(credit.Tag, dl.profile.joinArtists(job.Track.Contributors.Get(credit.Role))...)
+	}
+	if job.Album != nil {
+		t.Set("ALBUM", job.Album.Title)
+	}
+	if job.Number > 0 {
+		t.Set("TRACKNUMBER", strconv.Itoa(job.Number))
+	}
+	if job.Disc > 0 {
+		t.Set("DISCNUMBER", strconv.Itoa(job.Disc))
+		t.Set("TOTALDISCS", strconv.Itoa(job.TotalDiscs))
+	}
+	t.Set(songIDTag, strconv.Itoa(job.Track.ID))
+	t.Set(formatTag, FormatToFormatString(dl.format))
+	return t
+}
+
+// saveLyrics fetches the lyrics of a track if the profile wants them,
+// writing them to an .lrc file next to it and adding them to its tags.
+// Missing lyrics are not an error.
+func (dl *downloader) saveLyrics(job trackJob, t tags.Tags) {
+	if !dl.profile.LyricsFile && !dl.profile.EmbedLyrics {
+		return
+	}
+	lyrics, err := job.Track.GetLyrics()
+	if err == deezer.ErrNoLyrics {
+		return
+	} else if err != nil {
+		logrus.Warnf("couldn't get lyrics for %s: %s", job.Track.Title, err)
+		return
+	}
+
+	if dl.profile.LyricsFile && lyrics.Synced() {
+		lrcPath := strings.TrimSuffix(job.Path, filepath.Ext(job.Path)) + ".lrc"
+		if err := ioutil.WriteFile(lrcPath, []byte(lyrics.LRC()), 0644); err != nil {
+			logrus.Warnf("couldn't save lyrics to %s: %s", lrcPath, err)
+		}
+	}
+	if dl.profile.EmbedLyrics {
+		if lyrics.Synced() {
+			t.Set(tags.LyricsField, lyrics.LRC())
+		}
+		if lyrics.Text != "" {
+			t.Set(tags.UnsyncedLyricsField, lyrics.Text)
+		}
+	}
+}
+
+// makeDir creates a directory for downloads, unless this is a dry run
+func (dl *downloader) makeDir(dir string) error {
+	if dl.plan != nil {
+		return nil
+	}
+	return os.MkdirAll(dir, configDirPerms)
 }
 
 // downloadAlbum downloads all tracks in an album
-func downloadAlbum(format deezer.Format, ID int, api *deezer.API) error {
+func (dl *downloader) downloadAlbum(ID int) error {
 	// get album info
-	fmt.Println("\nGetting album info...")
-	album, err := api.GetAlbumData(ID)
+	dl.out.Println("\nGetting album info...")
+	album, err := dl.api.GetAlbumData(ID)
 	if err != nil {
 		return err
 	}
-	fmt.Println("Got album info")
-	fmt.Println("")
+	dl.out.Println("Got album info")
+	dl.out.Println("")
 
 	// get tracks
 	tracks, err := album.GetTracks()
 	if err != nil {
 		return err
 	}
+	dl.out.Event(ResolvedEvent, Event{
+		"type":   AlbumItem,
+		"id":     album.ID,
+		"title":  album.Title,
+		"tracks": len(tracks),
+	})
+
+	// make new dir for the album
+	albumDir, err := RenderTemplate(dl.profile.AlbumTemplate, NewAlbumTemplateData(album))
+	if err != nil {
+		return err
+	}
+	albumDir = filepath.Join(dl.profile.OutputDir, escapeFilename(albumDir))
+	if err := dl.makeDir(albumDir); err != nil {
+		return err
+	}
+
+	jobs, err := dl.albumJobs(album, tracks, albumDir)
+	if err != nil {
+		return err
+	}
+	if dl.profile.GetDiscLayout() == DiscLayoutFolders {
+		for _, job := range jobs {
+			if err := dl.makeDir(filepath.Dir(job.Path)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return dl.downloadJobs(album.Title, jobs)
+}
+
+// albumJobs works out where each track of an album is saved. Tracks
+// are numbered by their number on their disc, falling back to their
+// position in the album if Deezer doesn't know it. If the album has
+// more than one disc, the discs are kept apart according to the
+// profile's disc layout.
+func (dl *downloader) albumJobs(album *deezer.Album, tracks []*deezer.Track, albumDir string) ([]trackJob, error) {
+	totalDiscs := 1
+	for _, track := range tracks {
+		if track.DiskNumber > totalDiscs {
+			totalDiscs = track.DiskNumber
+		}
+	}
+	layout := dl.profile.GetDiscLayout()
+
+	var jobs []trackJob
+	for index, track := range tracks {
+		number := track.TrackNumber
+		if number <= 0 {
+			number = index + 1
+		}
+		disc := track.DiskNumber
+		if disc <= 0 {
+			disc = 1
+		}
+
+		data := NewTemplateData(track, album, number, dl.format)
+		data.DiscNumber = disc
+		data.TotalDiscs = totalDiscs
+		filename, err := CalculateFilename(dl.profile.TrackTemplate, data, dl.format)
+		if err != nil {
+			return nil, err
+		}
+
+		dir := albumDir
+		if totalDiscs > 1 {
+			switch layout {
+			case DiscLayoutPrefix:
+				filename = fmt.Sprintf("%d-%s", disc, filename)
+			case DiscLayoutFolders:
+				dir = filepath.Join(albumDir, fmt.Sprintf("CD%d", disc))
+			}
+		}
+
+		jobs = append(jobs, trackJob{
+			Track:      track,
+			Album:      album,
+			Number:     number,
+			Disc:       disc,
+			TotalDiscs: totalDiscs,
+			Path:       filepath.Join(dir, filename),
+		})
+	}
+	return jobs, nil
+}
+
+// downloadPlaylist downloads all tracks in a playlist, numbered by
+// their position in the playlist
+func (dl *downloader) downloadPlaylist(ID int) error {
+	// get playlist info
+	dl.out.Println("\nGetting playlist info...")
+	playlist, err := dl.api.GetPlaylistData(ID)
+	if err != nil {
+		return err
+	}
+	dl.out.Println("Got playlist info")
+	dl.out.Println("")
 
-	// make new dir for the album and CD to it
-	if err := os.Mkdir(album.Title, configDirPerms); err != nil {
-		logrus.Warnf("couldn't make new dir -- dir possibly exists? error: %s", err)
+	// get tracks
+	tracks, err := playlist.GetTracks()
+	if err != nil {
+		return err
 	}
-	if err := os.Chdir(album.Title); err != nil {
+	dl.out.Event(ResolvedEvent, Event{
+		"type":   PlaylistItem,
+		"id":     playlist.ID,
+		"title":  playlist.Title,
+		"tracks": len(tracks),
+	})
+
+	// make new dir for the playlist
+	playlistDir, err := RenderTemplate(dl.profile.AlbumTemplate, NewPlaylistTemplateData(playlist))
+	if err != nil {
+		return err
+	}
+	playlistDir = filepath.Join(dl.profile.OutputDir, escapeFilename(playlistDir))
+	if err := dl.makeDir(playlistDir); err != nil {
 		return err
 	}
 
 	// download all tracks
+	var jobs []trackJob
 	for index, track := range tracks {
-		if err := downloadTrack(format, track.ID, api); err != nil {
+		data := NewTemplateData(track, nil, index+1, dl.format)
+		data.Playlist = playlist.Title
+		filename, err := CalculateFilename(dl.profile.TrackTemplate, data, dl.format)
+		if err != nil {
 			return err
 		}
-		// rename to have track number at front
-		oldFilename := CalculateFilename(track, format)
-		newFilename := fmt.Sprintf("%02d - %s", index+1, oldFilename)
-		if err := os.Rename(oldFilename, newFilename); err != nil {
-			logrus.Warnf("couldn't rename file: %s", err)
-		}
+		jobs = append(jobs, trackJob{
+			Track:  track,
+			Number: index + 1,
+			Path:   filepath.Join(playlistDir, filename),
+		})
 	}
 
-	return nil
+	return dl.downloadJobs(playlist.Title, jobs)
 }
 
 func FormatStringToFormat(formatString string) deezer.Format {
+	format, err := ParseFormat(formatString)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	return format
+}
+
+// ParseFormat converts a format string to a format, returning an
+// error if it is invalid
+func ParseFormat(formatString string) (deezer.Format, error) {
 	var format deezer.Format
 	switch formatString {
 	case "FLAC":
@@ -217,9 +737,25 @@ func FormatStringToFormat(formatString string) deezer.Format {
 	case "MP3_128":
 		format = deezer.MP3_128
 	default:
-		logrus.Fatalf("invalid format: %s", formatString)
+		return 0, fmt.Errorf("invalid format: %s", formatString)
+	}
+	return format, nil
+}
+
+// FormatToFormatString is the inverse of FormatStringToFormat
+func FormatToFormatString(format deezer.Format) string {
+	switch format {
+	case deezer.FLAC:
+		return "FLAC"
+	case deezer.MP3_320:
+		return "MP3_320"
+	case deezer.MP3_256:
+		return "MP3_256"
+	case deezer.MP3_128:
+		return "MP3_128"
+	default:
+		return fmt.Sprintf("%d", format)
 	}
-	return format
 }
 
 func FormatExtension(format deezer.Format) string {
@@ -234,10 +770,16 @@ func FormatExtension(format deezer.Format) string {
 	return ""
 }
 
-func CalculateFilename(track *deezer.Track, format deezer.Format) string {
-	filename := track.Title + FormatExtension(format)
+// CalculateFilename renders the track template to get the filename
+// of a track, including the extension
+func CalculateFilename(template string, data *TemplateData, format deezer.Format) (string, error) {
+	name, err := RenderTemplate(template, data)
+	if err != nil {
+		return "", err
+	}
+	filename := name + FormatExtension(format)
 
-	return escapeFilename(filename)
+	return escapeFilename(filename), nil
 }
 
 // escapeFilename removes illegal characters from filenames
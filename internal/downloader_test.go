@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/joshbarrass/deezerdl/pkg/writetracker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/song" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("song data"))
+	}))
+	defer server.Close()
+
+	api, err := deezer.NewAPI(false)
+	assert.Equal(t, nil, err)
+	dir, err := ioutil.TempDir("", "deezerdl")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	outPath := filepath.Join(dir, "song.enc")
+
+	// a bad status leaves nothing behind
+	var progress []writetracker.Progress
+	reporter := writetracker.ReporterFunc(func(p writetracker.Progress) { progress = append(progress, p) })
+	err = DownloadFile(api, server.URL+"/missing", outPath, writetracker.NewWriteTracker(0, reporter), nil)
+	assert.NotEqual(t, nil, err)
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 0, len(files))
+
+	// the tracker is left for the caller to finish
+	err = DownloadFile(api, server.URL+"/song", outPath, writetracker.NewWriteTracker(0, reporter), nil)
+	assert.Equal(t, nil, err)
+	data, err := ioutil.ReadFile(outPath)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "song data", string(data))
+	_, err = os.Stat(outPath + ".part")
+	assert.True(t, os.IsNotExist(err))
+	for _, p := range progress {
+		assert.False(t, p.Done)
+	}
+}
+
+func TestNewTrackerAttempts(t *testing.T) {
+	var buf bytes.Buffer
+	progress := writetracker.NewManagerWriter(&buf, false)
+	progress.SetOverall("Album", 1)
+	dl := &downloader{out: &Output{writer: ioutil.Discard}, progress: progress, format: deezer.FLAC}
+	track := &deezer.Track{ID: 1, Title: "Track"}
+
+	// a failed attempt is dropped, so only the passing one is counted
+	_, discard := dl.newTracker(track)
+	discard()
+	tracker, _ := dl.newTracker(track)
+	tracker.Finish()
+	assert.Equal(t, 1, strings.Count(buf.String(), "1/1 tracks"))
+	assert.NotContains(t, buf.String(), "2/1 tracks")
+}
+
+func TestEachJob(t *testing.T) {
+	var jobs []trackJob
+	for i := 1; i <= 5; i++ {
+		jobs = append(jobs, trackJob{Track: &deezer.Track{ID: i, Title: "Track"}})
+	}
+	errFailed := errors.New("failed")
+
+	// failures don't stop the other jobs, however many run at once
+	for _, n := range []int{1, 2} {
+		var buf bytes.Buffer
+		dl := &downloader{jobs: n, out: &Output{writer: &buf}}
+		var mu sync.Mutex
+		var attempted []int
+		err := dl.eachJob(jobs, func(job trackJob) error {
+			mu.Lock()
+			attempted = append(attempted, job.Track.ID)
+			mu.Unlock()
+			if job.Track.ID%2 == 0 {
+				return errFailed
+			}
+			return nil
+		})
+		sort.Ints(attempted)
+		assert.Equal(t, errFailed, err, "jobs=%d", n)
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, attempted, "jobs=%d", n)
+		assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("Failed to download")), "jobs=%d", n)
+	}
+}
+
+func TestAlbumJobs(t *testing.T) {
+	album := &deezer.Album{ID: 1, Title: "Album"}
+	tracks := []*deezer.Track{
+		{ID: 1, Title: "First", TrackNumber: 1, DiskNumber: 1},
+		{ID: 2, Title: "Second", TrackNumber: 2, DiskNumber: 1},
+		{ID: 3, Title: "Third", TrackNumber: 1, DiskNumber: 2},
+	}
+	profile := NewProfile()
+	dl := &downloader{profile: profile, format: deezer.FLAC}
+
+	t.Run("Prefix", func(t *testing.T) {
+		jobs, err := dl.albumJobs(album, tracks, "Album")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, filepath.Join("Album", "1-02 - Second.flac"), jobs[1].Path)
+		assert.Equal(t, filepath.Join("Album", "2-01 - Third.flac"), jobs[2].Path)
+		assert.Equal(t, 1, jobs[2].Number)
+		assert.Equal(t, 2, jobs[2].Disc)
+		assert.Equal(t, 2, jobs[2].TotalDiscs)
+
+		fileTags := dl.trackTags(jobs[2])
+		assert.Equal(t, "1", fileTags.Get("TRACKNUMBER"))
+		assert.Equal(t, "2", fileTags.Get("DISCNUMBER"))
+		assert.Equal(t, "2", fileTags.Get("TOTALDISCS"))
+	})
+
+	t.Run("Folders", func(t *testing.T) {
+		profile.DiscLayout = DiscLayoutFolders
+		defer func() { profile.DiscLayout = "" }()
+		jobs, err := dl.albumJobs(album, tracks, "Album")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, filepath.Join("Album", "CD2", "01 - Third.flac"), jobs[2].Path)
+	})
+
+	t.Run("None", func(t *testing.T) {
+		profile.DiscLayout = DiscLayoutNone
+		profile.TrackTemplate = "{{.DiscNumber}}.{{.TrackNumber}} {{.Title}}"
+		defer func() {
+			profile.DiscLayout = ""
+			profile.TrackTemplate = DefaultTrackTemplate
+		}()
+		jobs, err := dl.albumJobs(album, tracks, "Album")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, filepath.Join("Album", "2.1 Third.flac"), jobs[2].Path)
+	})
+
+	t.Run("Single Disc", func(t *testing.T) {
+		// no disc or track numbers known, so fall back to the position
+		single := []*deezer.Track{{ID: 1, Title: "First"}, {ID: 2, Title: "Second"}}
+		jobs, err := dl.albumJobs(album, single, "Album")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, filepath.Join("Album", "02 - Second.flac"), jobs[1].Path)
+		assert.Equal(t, 1, jobs[1].TotalDiscs)
+	})
+}
+
+func TestTrackTagsCredits(t *testing.T) {
+	track := &deezer.Track{
+		ID:      3135553,
+		Title:   "One More Time",
+		Artists: []deezer.TrackArtist{{ID: 27, Name: "Daft Punk"}, {ID: 1, Name: "Romanthony"}},
+		Contributors: deezer.TrackContributors{
+			deezer.RoleComposer:  {"Thomas Bangalter", "Guy-Manuel de Homem-Christo"},
+			deezer.RoleAuthor:    {"Anthony Moore"},
+			deezer.RoleFeaturing: {"Romanthony"},
+		},
+	}
+	profile := NewProfile()
+	dl := &downloader{profile: profile, format: deezer.FLAC}
+
+	fileTags := dl.trackTags(trackJob{Track: track})
+	assert.Equal(t, []string{"Daft Punk", "Romanthony"}, fileTags["ARTIST"])
+	assert.Equal(t, []string{"Thomas Bangalter", "Guy-Manuel de Homem-Christo"}, fileTags["COMPOSER"])
+	assert.Equal(t, []string{"Anthony Moore"}, fileTags["LYRICIST"])
+	assert.Equal(t, []string{"Romanthony"}, fileTags["PERFORMER"])
+	_, ok := fileTags["PRODUCER"]
+	assert.False(t, ok)
+
+	profile.ArtistSeparator = "; "
+	fileTags = dl.trackTags(trackJob{Track: track})
+	assert.Equal(t, []string{"Daft Punk; Romanthony"}, fileTags["ARTIST"])
+	assert.Equal(t, []string{"Thomas Bangalter; Guy-Manuel de Homem-Christo"}, fileTags["COMPOSER"])
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+// Actions a dry run reports for each track
+const (
+	PlanDownload = "download"
+	PlanSkip     = "skip"
+)
+
+// dryRunPlan collects the tracks that a dry run would download, so
+// that totals and conflicts can be reported across a whole batch
+type dryRunPlan struct {
+	Tracks    int
+	Downloads int
+	Skips     int
+	Conflicts int
+	// Bytes is the estimated size of everything to be downloaded
+	Bytes int64
+	// Unknown counts downloads whose size isn't known
+	Unknown int
+
+	// paths maps each target path to the track that will be saved
+	// there
+	paths map[string]int
+	mu    sync.Mutex
+}
+
+func newDryRunPlan() *dryRunPlan {
+	return &dryRunPlan{
+		paths: make(map[string]int),
+	}
+}
+
+// planJobs prints what would be done with each track instead of
+// downloading it. Nothing is written to disk and the CDN is never
+// contacted.
+func (dl *downloader) planJobs(title string, jobs []trackJob) error {
+	if title != "" {
+		dl.out.Printf("%s: %d tracks\n", title, len(jobs))
+	}
+	for _, job := range jobs {
+		dl.planJob(job)
+	}
+	return nil
+}
+
+// planJob works out and reports what would happen to a single track
+func (dl *downloader) planJob(job trackJob) {
+	plan := dl.plan
+	plan.mu.Lock()
+	defer plan.mu.Unlock()
+
+	track := job.Track
+	size := track.FileSize(dl.format)
+	action := PlanDownload
+	var conflict string
+
+	entry, archived := dl.archive.Lookup(track.ID, dl.format)
+	if archived && !dl.force {
+		action = PlanSkip
+	} else if otherID, ok := plan.paths[job.Path]; ok {
+		conflict = fmt.Sprintf("same path as track %d", otherID)
+	} else if _, err := os.Stat(job.Path); err == nil && !archived {
+		conflict = "file exists and would be overwritten"
+	}
+
+	plan.Tracks++
+	if action == PlanSkip {
+		plan.Skips++
+	} else {
+		plan.Downloads++
+		plan.paths[job.Path] = track.ID
+		if size > 0 {
+			plan.Bytes += size
+		} else {
+			plan.Unknown++
+		}
+	}
+	if conflict != "" {
+		plan.Conflicts++
+	}
+
+	event := Event{
+		"id":     track.ID,
+		"title":  track.Title,
+		"format": FormatToFormatString(dl.format),
+		"size":   size,
+		"path":   job.Path,
+		"action": action,
+	}
+	if conflict != "" {
+		event["conflict"] = conflict
+	}
+	dl.out.Event(PlannedEvent, event)
+
+	switch {
+	case action == PlanSkip:
+		dl.out.Printf("  skip      %s -- already downloaded to %s\n", track.Title, entry.Path)
+	case conflict != "":
+		dl.out.Printf("  CONFLICT  %s [%s, %s] -> %s (%s)\n", track.Title, FormatToFormatString(dl.format), formatSize(size), job.Path, conflict)
+	default:
+		dl.out.Printf("  download  %s [%s, %s] -> %s\n", track.Title, FormatToFormatString(dl.format), formatSize(size), job.Path)
+	}
+}
+
+// printTotals prints the totals of the plan
+func (plan *dryRunPlan) printTotals(out *Output) {
+	plan.mu.Lock()
+	defer plan.mu.Unlock()
+	out.Printf("  Tracks: %d to download, %d skipped\n", plan.Downloads, plan.Skips)
+	estimate := humanize.Bytes(uint64(plan.Bytes))
+	if plan.Unknown > 0 {
+		estimate += fmt.Sprintf(" (plus %d of unknown size)", plan.Unknown)
+	}
+	out.Printf("  Estimated size: %s\n", estimate)
+	if plan.Conflicts > 0 {
+		out.Printf("  Conflicts: %d\n", plan.Conflicts)
+	}
+}
+
+// formatSize formats a size in bytes, which is 0 if unknown
+func formatSize(size int64) string {
+	if size <= 0 {
+		return "unknown size"
+	}
+	return humanize.Bytes(uint64(size))
+}
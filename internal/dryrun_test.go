@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deezerdl")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	archive := &Archive{
+		Entries: make(map[string]*ArchiveEntry),
+		path:    filepath.Join(dir, archiveFile),
+	}
+	archived := filepath.Join(dir, "archived.flac")
+	assert.Equal(t, nil, ioutil.WriteFile(archived, []byte("fLaC"), 0644))
+	_, err = archive.Add(1, deezer.FLAC, archived)
+	assert.Equal(t, nil, err)
+	existing := filepath.Join(dir, "existing.flac")
+	assert.Equal(t, nil, ioutil.WriteFile(existing, []byte("fLaC"), 0644))
+
+	var buf bytes.Buffer
+	dl := &downloader{
+		format:  deezer.FLAC,
+		archive: archive,
+		out:     &Output{writer: &buf},
+		plan:    newDryRunPlan(),
+	}
+	track := func(ID int, size int64) *deezer.Track {
+		return &deezer.Track{ID: ID, Title: "Track", FileSizes: map[deezer.Format]int64{deezer.FLAC: size}}
+	}
+	jobs := []trackJob{
+		{Track: track(1, 100), Path: filepath.Join(dir, "archived.flac")},
+		{Track: track(2, 200), Path: filepath.Join(dir, "new.flac")},
+		{Track: track(3, 300), Path: filepath.Join(dir, "new.flac")},
+		{Track: track(4, 0), Path: existing},
+	}
+	assert.Equal(t, nil, dl.downloadJobs("Album", jobs))
+
+	plan := dl.plan
+	assert.Equal(t, 4, plan.Tracks)
+	assert.Equal(t, 3, plan.Downloads)
+	assert.Equal(t, 1, plan.Skips)
+	assert.Equal(t, 2, plan.Conflicts)
+	assert.Equal(t, int64(500), plan.Bytes)
+	assert.Equal(t, 1, plan.Unknown)
+	assert.Contains(t, buf.String(), "same path as track 2")
+	assert.Contains(t, buf.String(), "would be overwritten")
+
+	// nothing should have been written
+	_, err = os.Stat(filepath.Join(dir, "new.flac"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -1,6 +1,10 @@
 package internal
 
-import "os"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
 
 // https://stackoverflow.com/questions/10510691/how-to-check-whether-a-file-or-directory-exists
 
@@ -15,3 +19,28 @@ func FileExists(path string) (bool, error) {
 	}
 	return true, err
 }
+
+// writeFileAtomic writes data to a temporary file next to path and
+// renames it into place, so that a crash part way through never
+// leaves a truncated file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if err := tmpFile.Chmod(perm); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,456 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/docopt/docopt-go"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/sirupsen/logrus"
+)
+
+// infoCoverSize is the size in pixels of the track cover URL shown
+const infoCoverSize = 1000
+
+// allFormats lists every format, best first
+var allFormats = []deezer.Format{deezer.FLAC, deezer.MP3_320, deezer.MP3_256, deezer.MP3_128}
+
+// FormatInfo is the size of a track or album in one format
+type FormatInfo struct {
+	Format string `json:"format"`
+	Size   int64  `json:"size"`
+}
+
+// TrackInfo is the metadata shown by "info track"
+type TrackInfo struct {
+	ID       int          `json:"id"`
+	Title    string       `json:"title"`
+	Artists  []string     `json:"artists"`
+	Album    string       `json:"album,omitempty"`
+	AlbumID  int          `json:"album_id,omitempty"`
+	Number   int          `json:"track_number,omitempty"`
+	Disc     int          `json:"disc_number,omitempty"`
+	Duration int          `json:"duration"`
+	ISRC     string       `json:"isrc,omitempty"`
+	Cover    string       `json:"cover,omitempty"`
+	Formats  []FormatInfo `json:"formats"`
+}
+
+// AlbumInfo is the metadata shown by "info album"
+type AlbumInfo struct {
+	ID          int               `json:"id"`
+	Title       string            `json:"title"`
+	Artist      string            `json:"artist"`
+	Link        string            `json:"link"`
+	ReleaseDate string            `json:"release_date,omitempty"`
+	RecordType  string            `json:"record_type"`
+	Label       string            `json:"label,omitempty"`
+	UPC         string            `json:"upc,omitempty"`
+	Genres      []string          `json:"genres"`
+	Duration    int               `json:"duration"`
+	Covers      map[string]string `json:"covers"`
+	Formats     []FormatInfo      `json:"formats"`
+	Tracks      []*TrackInfo      `json:"tracks"`
+}
+
+// ArtistInfo is the metadata shown by "info artist"
+type ArtistInfo struct {
+	ID       int             `json:"id"`
+	Name     string          `json:"name"`
+	Link     string          `json:"link"`
+	Fans     int             `json:"fans"`
+	Picture  string          `json:"picture"`
+	Releases []*ReleaseGroup `json:"releases"`
+}
+
+// ReleaseGroup holds an artist's releases of one record type, newest
+// year first
+type ReleaseGroup struct {
+	Type  string         `json:"type"`
+	Years []*ReleaseYear `json:"years"`
+	// Count is the number of releases in all years
+	Count int `json:"count"`
+}
+
+// ReleaseYear holds the releases of one year, newest first
+type ReleaseYear struct {
+	Year     int              `json:"year"`
+	Releases []deezer.Release `json:"releases"`
+}
+
+// releaseTypes lists the record types in the order they are shown
+var releaseTypes = []string{
+	deezer.RecordTypeAlbum,
+	deezer.RecordTypeEP,
+	deezer.RecordTypeSingle,
+	deezer.RecordTypeCompilation,
+}
+
+// releaseHeadings are the headings of the known record types
+var releaseHeadings = map[string]string{
+	deezer.RecordTypeAlbum:       "Albums",
+	deezer.RecordTypeEP:          "EPs",
+	deezer.RecordTypeSingle:      "Singles",
+	deezer.RecordTypeCompilation: "Compilations",
+}
+
+// NewTrackInfo collects the metadata of a track
+func NewTrackInfo(track *deezer.Track) *TrackInfo {
+	info := &TrackInfo{
+		ID:       track.ID,
+		Title:    track.Title,
+		Artists:  track.ArtistNames(),
+		Album:    track.AlbumTitle,
+		AlbumID:  track.AlbumID,
+		Number:   track.TrackNumber,
+		Duration: track.Duration,
+		ISRC:     track.ISRC,
+		Cover:    track.CoverURL(infoCoverSize),
+		Formats:  []FormatInfo{},
+	}
+	for _, format := range allFormats {
+		if size := track.FileSize(format); size > 0 {
+			info.Formats = append(info.Formats, FormatInfo{
+				Format: FormatToFormatString(format),
+				Size:   size,
+			})
+		}
+	}
+	return info
+}
+
+// NewAlbumInfo collects the metadata of an album and its tracks. A
+// format is only listed if every track is available in it, with the
+// total size of all tracks.
+func NewAlbumInfo(album *deezer.Album, tracks []*deezer.Track) *AlbumInfo {
+	info := &AlbumInfo{
+		ID:         album.ID,
+		Title:      album.Title,
+		Artist:     album.Artist.Name,
+		Link:       album.Link,
+		RecordType: album.RecordType,
+		Label:      album.Label,
+		UPC:        album.UPC,
+		Genres:     album.GenreNames(),
+		Covers: map[string]string{
+			"small":  album.Covers.Small,
+			"medium": album.Covers.Medium,
+			"big":    album.Covers.Big,
+			"xl":     album.Covers.XL,
+		},
+		Formats: []FormatInfo{},
+		Tracks:  []*TrackInfo{},
+	}
+	if !album.Date.IsZero() {
+		info.ReleaseDate = album.Date.Format("2006-01-02")
+	}
+
+	for _, format := range allFormats {
+		var total int64
+		for _, track := range tracks {
+			size := track.FileSize(format)
+			if size <= 0 {
+				total = 0
+				break
+			}
+			total += size
+		}
+		if total > 0 {
+			info.Formats = append(info.Formats, FormatInfo{
+				Format: FormatToFormatString(format),
+				Size:   total,
+			})
+		}
+	}
+
+	// number tracks the same way as downloads do
+	for i, track := range tracks {
+		trackInfo := NewTrackInfo(track)
+		trackInfo.Number = track.TrackNumber
+		if trackInfo.Number <= 0 {
+			trackInfo.Number = i + 1
+		}
+		trackInfo.Disc = track.DiskNumber
+		if trackInfo.Disc <= 0 {
+			trackInfo.Disc = 1
+		}
+		info.Tracks = append(info.Tracks, trackInfo)
+		info.Duration += track.Duration
+	}
+	return info
+}
+
+// NewArtistInfo collects the metadata of an artist, grouping their
+// releases by record type and then by year
+func NewArtistInfo(artist *deezer.Artist, releases []deezer.Release) *ArtistInfo {
+	info := &ArtistInfo{
+		ID:       artist.ID,
+		Name:     artist.Name,
+		Link:     artist.Link,
+		Fans:     artist.NumFans,
+		Picture:  artist.Pictures.XL,
+		Releases: []*ReleaseGroup{},
+	}
+
+	sorted := make([]deezer.Release, len(releases))
+	copy(sorted, releases)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ReleaseDate > sorted[j].ReleaseDate
+	})
+
+	groups := make(map[string]*ReleaseGroup)
+	var order []string
+	for _, release := range sorted {
+		group, ok := groups[release.RecordType]
+		if !ok {
+			group = &ReleaseGroup{Type: release.RecordType}
+			groups[release.RecordType] = group
+			order = append(order, release.RecordType)
+		}
+		year := release.Year()
+		if n := len(group.Years); n == 0 || group.Years[n-1].Year != year {
+			group.Years = append(group.Years, &ReleaseYear{Year: year})
+		}
+		last := group.Years[len(group.Years)-1]
+		last.Releases = append(last.Releases, release)
+		group.Count++
+	}
+
+	// known types first, in the usual order, then anything else
+	for _, recordType := range releaseTypes {
+		if group, ok := groups[recordType]; ok {
+			info.Releases = append(info.Releases, group)
+			delete(groups, recordType)
+		}
+	}
+	for _, recordType := range order {
+		if group, ok := groups[recordType]; ok {
+			info.Releases = append(info.Releases, group)
+		}
+	}
+	return info
+}
+
+// Info prints the metadata of a track, album or artist
+func Info(opts docopt.Opts, config *Configuration) {
+	jsonMode, _ := opts.Bool("--json")
+
+	itemType := TrackItem
+	if album, _ := opts.Bool(AlbumItem); album {
+		itemType = AlbumItem
+	} else if artist, _ := opts.Bool(ArtistType); artist {
+		itemType = ArtistType
+	}
+	arg, err := opts.String("<ID>")
+	if err != nil {
+		logrus.Fatalf("failed to parse arguments: %s", err)
+	}
+	ID, err := parseIDArg(arg, itemType)
+	if err != nil {
+		logrus.Fatalf("invalid %s: %s", itemType, arg)
+	}
+
+	api, err := NewLoggedInAPI(config)
+	if err != nil {
+		logrus.Fatalf("failed to log in: %s", err)
+	}
+
+	var info interface{}
+	switch itemType {
+	case TrackItem:
+		track, err := api.GetSongData(ID)
+		if err != nil {
+			logrus.Fatalf("failed to get track: %s", err)
+		}
+		info = NewTrackInfo(track)
+	case AlbumItem:
+		album, err := api.GetAlbumData(ID)
+		if err != nil {
+			logrus.Fatalf("failed to get album: %s", err)
+		}
+		tracks, err := album.GetTracks()
+		if err != nil {
+			logrus.Fatalf("failed to get album tracks: %s", err)
+		}
+		info = NewAlbumInfo(album, tracks)
+	case ArtistType:
+		artist, err := api.GetArtistData(ID)
+		if err != nil {
+			logrus.Fatalf("failed to get artist: %s", err)
+		}
+		releases, err := artist.GetReleases()
+		if err != nil {
+			logrus.Fatalf("failed to get artist releases: %s", err)
+		}
+		info = NewArtistInfo(artist, releases)
+	}
+
+	if jsonMode {
+		if err := json.NewEncoder(os.Stdout).Encode(info); err != nil {
+			logrus.Fatalf("failed to write JSON: %s", err)
+		}
+		return
+	}
+	switch info := info.(type) {
+	case *TrackInfo:
+		printTrackInfo(os.Stdout, info)
+	case *AlbumInfo:
+		printAlbumInfo(os.Stdout, info)
+	case *ArtistInfo:
+		printArtistInfo(os.Stdout, info)
+	}
+}
+
+// parseIDArg parses an ID given on the command line, which can also be
+// a deezer URL of the expected type
+func parseIDArg(arg, itemType string) (int, error) {
+	if ID, err := strconv.Atoi(arg); err == nil {
+		return ID, nil
+	}
+	urlType, ID, err := parseDeezerURL(arg)
+	if err != nil {
+		return 0, err
+	}
+	if urlType != itemType {
+		return 0, ErrBadItem
+	}
+	return ID, nil
+}
+
+// printTrackInfo prints a track as a table
+func printTrackInfo(w io.Writer, info *TrackInfo) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%d\n", info.ID)
+	fmt.Fprintf(tw, "Title:\t%s\n", info.Title)
+	fmt.Fprintf(tw, "Artists:\t%s\n", strings.Join(info.Artists, ", "))
+	if info.Album != "" {
+		fmt.Fprintf(tw, "Album:\t%s (%d)\n", info.Album, info.AlbumID)
+	}
+	if info.Number > 0 {
+		fmt.Fprintf(tw, "Track:\t%d\n", info.Number)
+	}
+	fmt.Fprintf(tw, "Duration:\t%s\n", formatDuration(info.Duration))
+	if info.ISRC != "" {
+		fmt.Fprintf(tw, "ISRC:\t%s\n", info.ISRC)
+	}
+	if info.Cover != "" {
+		fmt.Fprintf(tw, "Cover:\t%s\n", info.Cover)
+	}
+	fmt.Fprintf(tw, "Formats:\t%s\n", formatList(info.Formats))
+	tw.Flush()
+}
+
+// printAlbumInfo prints an album and its track list as tables
+func printAlbumInfo(w io.Writer, info *AlbumInfo) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%d\n", info.ID)
+	fmt.Fprintf(tw, "Title:\t%s\n", info.Title)
+	fmt.Fprintf(tw, "Artist:\t%s\n", info.Artist)
+	fmt.Fprintf(tw, "Type:\t%s\n", info.RecordType)
+	if info.ReleaseDate != "" {
+		fmt.Fprintf(tw, "Released:\t%s\n", info.ReleaseDate)
+	}
+	if info.Label != "" {
+		fmt.Fprintf(tw, "Label:\t%s\n", info.Label)
+	}
+	if info.UPC != "" {
+		fmt.Fprintf(tw, "UPC:\t%s\n", info.UPC)
+	}
+	if len(info.Genres) > 0 {
+		fmt.Fprintf(tw, "Genres:\t%s\n", strings.Join(info.Genres, ", "))
+	}
+	fmt.Fprintf(tw, "Duration:\t%s\n", formatDuration(info.Duration))
+	fmt.Fprintf(tw, "Link:\t%s\n", info.Link)
+	fmt.Fprintf(tw, "Cover:\t%s\n", info.Covers["xl"])
+	fmt.Fprintf(tw, "Formats:\t%s\n", formatList(info.Formats))
+	tw.Flush()
+
+	// the disc is only shown for albums with more than one
+	multiDisc := false
+	for _, track := range info.Tracks {
+		if track.Disc > 1 {
+			multiDisc = true
+		}
+	}
+
+	fmt.Fprintln(w, "")
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if multiDisc {
+		fmt.Fprint(tw, "Disc\t")
+	}
+	fmt.Fprintln(tw, "#\tTitle\tArtists\tDuration\tID")
+	for _, track := range info.Tracks {
+		if multiDisc {
+			fmt.Fprintf(tw, "%d\t", track.Disc)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\n", track.Number, track.Title, strings.Join(track.Artists, ", "), formatDuration(track.Duration), track.ID)
+	}
+	tw.Flush()
+}
+
+// printArtistInfo prints an artist and their releases, grouped by
+// type and year
+func printArtistInfo(w io.Writer, info *ArtistInfo) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%d\n", info.ID)
+	fmt.Fprintf(tw, "Name:\t%s\n", info.Name)
+	fmt.Fprintf(tw, "Fans:\t%s\n", humanize.Comma(int64(info.Fans)))
+	fmt.Fprintf(tw, "Link:\t%s\n", info.Link)
+	fmt.Fprintf(tw, "Picture:\t%s\n", info.Picture)
+	tw.Flush()
+
+	for _, group := range info.Releases {
+		fmt.Fprintf(w, "\n%s (%d)\n", releaseHeading(group.Type), group.Count)
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for _, year := range group.Years {
+			if year.Year > 0 {
+				fmt.Fprintf(tw, "  %d\n", year.Year)
+			} else {
+				fmt.Fprintln(tw, "  Unknown year")
+			}
+			for _, release := range year.Releases {
+				fmt.Fprintf(tw, "    %s\t%d\n", release.Title, release.ID)
+			}
+		}
+		tw.Flush()
+	}
+}
+
+// releaseHeading returns the heading for a record type. Types that
+// are not known are shown as they are, with a capital letter.
+func releaseHeading(recordType string) string {
+	if heading, ok := releaseHeadings[recordType]; ok || recordType == "" {
+		return heading
+	}
+	first, size := utf8.DecodeRuneInString(recordType)
+	return string(unicode.ToUpper(first)) + recordType[size:]
+}
+
+// formatList formats the available formats and their sizes
+func formatList(formats []FormatInfo) string {
+	if len(formats) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(formats))
+	for i, format := range formats {
+		parts[i] = fmt.Sprintf("%s (%s)", format.Format, humanize.Bytes(uint64(format.Size)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatDuration formats a duration in seconds as m:ss or h:mm:ss
+func formatDuration(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
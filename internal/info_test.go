@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlbumInfo(t *testing.T) {
+	album := &deezer.Album{
+		ID:     302127,
+		Title:  "Discovery",
+		Artist: deezer.Contributor{ID: 27, Name: "Daft Punk"},
+		Date:   time.Date(2001, 3, 7, 0, 0, 0, 0, time.UTC),
+	}
+	tracks := []*deezer.Track{
+		{
+			ID:        3135553,
+			Title:     "One More Time",
+			Artist:    "Daft Punk",
+			Duration:  320,
+			FileSizes: map[deezer.Format]int64{deezer.FLAC: 1000, deezer.MP3_320: 100},
+		},
+		{
+			ID:        3135554,
+			Title:     "Aerodynamic",
+			Artist:    "Daft Punk",
+			Duration:  212,
+			FileSizes: map[deezer.Format]int64{deezer.MP3_320: 50},
+		},
+	}
+
+	info := NewAlbumInfo(album, tracks)
+	assert.Equal(t, "2001-03-07", info.ReleaseDate)
+	assert.Equal(t, 532, info.Duration)
+	assert.Equal(t, []FormatInfo{{Format: "MP3_320", Size: 150}}, info.Formats, "FLAC isn't available for every track")
+	assert.Equal(t, 2, info.Tracks[1].Number)
+	assert.Equal(t, []string{"Daft Punk"}, info.Tracks[0].Artists)
+	assert.Equal(t, 2, len(info.Tracks[0].Formats))
+
+	var buf bytes.Buffer
+	printAlbumInfo(&buf, info)
+	assert.Contains(t, buf.String(), "MP3_320 (150 B)")
+	assert.Contains(t, buf.String(), "Aerodynamic")
+	assert.Contains(t, buf.String(), "\n#  Title")
+}
+
+func TestAlbumInfoDiscs(t *testing.T) {
+	album := &deezer.Album{ID: 1, Title: "Album"}
+	tracks := []*deezer.Track{
+		{ID: 1, Title: "First", TrackNumber: 1, DiskNumber: 1},
+		{ID: 2, Title: "Second", TrackNumber: 2, DiskNumber: 1},
+		{ID: 3, Title: "Third", TrackNumber: 1, DiskNumber: 2},
+	}
+
+	info := NewAlbumInfo(album, tracks)
+	assert.Equal(t, 1, info.Tracks[2].Number)
+	assert.Equal(t, 2, info.Tracks[2].Disc)
+
+	var buf bytes.Buffer
+	printAlbumInfo(&buf, info)
+	assert.Contains(t, buf.String(), "\nDisc  #  Title")
+}
+
+func TestParseIDArg(t *testing.T) {
+	ID, err := parseIDArg("3135553", TrackItem)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3135553, ID)
+
+	ID, err = parseIDArg("https://www.deezer.com/en/album/302127", AlbumItem)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 302127, ID)
+
+	_, err = parseIDArg("https://www.deezer.com/en/album/302127", TrackItem)
+	assert.Equal(t, ErrBadItem, err)
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "5:20", formatDuration(320))
+	assert.Equal(t, "0:07", formatDuration(7))
+	assert.Equal(t, "1:01:01", formatDuration(3661))
+}
+
+func TestArtistInfo(t *testing.T) {
+	artist := &deezer.Artist{ID: 27, Name: "Daft Punk"}
+	releases := []deezer.Release{
+		{ID: 302127, Title: "Discovery", ReleaseDate: "2001-03-07", RecordType: deezer.RecordTypeAlbum},
+		{ID: 6710379, Title: "Get Lucky", ReleaseDate: "2013-04-19", RecordType: deezer.RecordTypeSingle},
+		{ID: 6575789, Title: "Random Access Memories", ReleaseDate: "2013-05-20", RecordType: deezer.RecordTypeAlbum},
+		{ID: 1, Title: "Live", ReleaseDate: "2013-01-01", RecordType: "live"},
+		{ID: 2, Title: "One More Time", ReleaseDate: "2001-01-01", RecordType: deezer.RecordTypeSingle},
+	}
+
+	info := NewArtistInfo(artist, releases)
+	assert.Equal(t, 3, len(info.Releases))
+	albums := info.Releases[0]
+	assert.Equal(t, deezer.RecordTypeAlbum, albums.Type)
+	assert.Equal(t, 2, albums.Count)
+	assert.Equal(t, 2013, albums.Years[0].Year, "newest first")
+	assert.Equal(t, 6575789, albums.Years[0].Releases[0].ID)
+	assert.Equal(t, deezer.RecordTypeSingle, info.Releases[1].Type)
+	assert.Equal(t, "live", info.Releases[2].Type, "unknown types come last")
+
+	var buf bytes.Buffer
+	printArtistInfo(&buf, info)
+	assert.Contains(t, buf.String(), "Albums (2)")
+	assert.Contains(t, buf.String(), "Live (1)")
+}
+
+func TestReleaseHeading(t *testing.T) {
+	assert.Equal(t, "EPs", releaseHeading(deezer.RecordTypeEP))
+	assert.Equal(t, "Compilations", releaseHeading(deezer.RecordTypeCompilation))
+	assert.Equal(t, "Bundle", releaseHeading("bundle"))
+	assert.Equal(t, "", releaseHeading(""))
+}
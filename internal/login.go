@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docopt/docopt-go"
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+// Login reads the arl cookie from the arguments, an interactive
+// prompt or stdin, checks that Deezer accepts it and saves it to the
+// secret store
+func Login(opts docopt.Opts, config *Configuration) {
+	arl, err := readARL(opts)
+	if err != nil {
+		logrus.Fatalf("failed to read arl cookie: %s", err)
+	}
+
+	// check the cookie before saving it
+	api, err := deezer.NewAPI(false)
+	if err != nil {
+		logrus.Fatalf("failed to create api: %s", err)
+	}
+	if err := api.CookieLogin(arl); err != nil {
+		logrus.Fatalf("failed to log in: %s", err)
+	}
+
+	if err := config.SetARL(arl); err != nil {
+		logrus.Fatalf("failed to save arl cookie: %s", err)
+	}
+	if store, err := LoadSessionStore(); err != nil {
+		logrus.Warnf("failed to open session store: %s", err)
+	} else if err := store.Set(config.ProfileName(), api.Session()); err != nil {
+		logrus.Warnf("failed to save session: %s", err)
+	}
+	printUser(api.CurrentUser())
+	fmt.Println("Saved arl! You can now use the rest of the program.")
+}
+
+// WhoAmI logs in with the saved arl cookie and prints the account
+// details
+func WhoAmI(opts docopt.Opts, config *Configuration) {
+	api, err := NewLoggedInAPI(config)
+	if err != nil {
+		logrus.Fatalf("failed to log in: %s", err)
+	}
+	printUser(api.CurrentUser())
+}
+
+// NewLoggedInAPI creates an API and logs in using the arl cookie from
+// the secret store, with the configured rate limits and the metadata
+// cache unless it is turned off.
+// The session saved by an earlier run is reused if
+// it has not expired, and any new session is saved for the next run.
+func NewLoggedInAPI(config *Configuration) (*deezer.API, error) {
+	arl, err := config.GetARL()
+	if err != nil {
+		return nil, err
+	}
+
+	api, err := deezer.NewAPI(false)
+	if err != nil {
+		return nil, err
+	}
+	config.useCache(api)
+	config.useRateLimits(api)
+
+	// a broken session store only costs a fresh login
+	profile := config.ProfileName()
+	store, err := LoadSessionStore()
+	if err != nil {
+		logrus.Warnf("failed to open session store: %s", err)
+	}
+	if store != nil {
+		api.OnSessionChange(saveSession(store, profile))
+		session, err := store.Get(profile)
+		if err != nil {
+			logrus.Warnf("failed to load session: %s", err)
+		} else if err := api.ResumeSession(session, arl); err == nil {
+			return api, nil
+		}
+	}
+
+	if err := api.CookieLogin(arl); err != nil {
+		return nil, err
+	}
+	return api, nil
+}
+
+// saveSession returns a session handler that keeps the profile's
+// saved session up to date
+func saveSession(store *SessionStore, profile string) func(*deezer.Session) {
+	return func(session *deezer.Session) {
+		if err := store.Update(profile, session); err != nil {
+			logrus.Warnf("failed to save session: %s", err)
+		}
+	}
+}
+
+// printUser prints the account details of a user
+func printUser(user *deezer.User) {
+	lossless := "no"
+	if user.LosslessAllowed {
+		lossless = "yes"
+	}
+	fmt.Printf("Logged in as %s (user ID %d)\n", user.Name, user.ID)
+	fmt.Printf("Subscription: %s\n", user.Offer)
+	fmt.Printf("Lossless streaming: %s\n", lossless)
+}
+
+// readARL gets the arl from the command line if it was given there,
+// otherwise prompts for it on a terminal or reads a line from stdin
+func readARL(opts docopt.Opts) (string, error) {
+	if arl, err := opts.String("<arl>"); err == nil && arl != "" {
+		logrus.Warn("passing the arl as an argument leaves it in your shell history -- run \"deezerdl login\" without it to be prompted instead")
+		return arl, nil
+	}
+
+	var arl string
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Enter your arl cookie: ")
+		data, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr, "")
+		if err != nil {
+			return "", err
+		}
+		arl = string(data)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		arl = line
+	}
+
+	arl = strings.TrimSpace(arl)
+	if arl == "" {
+		return "", errors.New("arl cookie is empty")
+	}
+	return arl, nil
+}
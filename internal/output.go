@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joshbarrass/deezerdl/pkg/writetracker"
+)
+
+// Event names used in JSON output
+const (
+	ResolvedEvent      = "resolved"
+	TrackStartedEvent  = "track_started"
+	ProgressEvent      = "progress"
+	TrackFinishedEvent = "track_finished"
+	TrackSkippedEvent  = "track_skipped"
+	TrackRetryEvent    = "track_retry"
+	PlannedEvent       = "planned"
+	ErrorEvent         = "error"
+	SummaryEvent       = "summary"
+)
+
+// progressEventInterval limits how often progress events are emitted
+// for a single download
+const progressEventInterval = 500 * time.Millisecond
+
+// Event holds the fields of a JSON output event
+type Event map[string]interface{}
+
+// Output sends messages either as human-readable text or as
+// newline-delimited JSON events. In JSON mode, stdout only carries
+// events and human-readable text is suppressed; warnings still go to
+// stderr through logrus.
+type Output struct {
+	JSON   bool
+	writer io.Writer
+	mu     sync.Mutex
+	// progress, if set, is used to print text above the progress bars
+	progress *writetracker.Manager
+}
+
+// NewOutput creates an Output writing to stdout
+func NewOutput(jsonMode bool) *Output {
+	return &Output{
+		JSON:   jsonMode,
+		writer: os.Stdout,
+	}
+}
+
+// Printf prints human-readable text. It does nothing in JSON mode.
+func (out *Output) Printf(format string, a ...interface{}) {
+	if out.JSON {
+		return
+	}
+	if out.progress != nil {
+		out.progress.Printf(format, a...)
+		return
+	}
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	fmt.Fprintf(out.writer, format, a...)
+}
+
+// Println prints a line of human-readable text. It does nothing in
+// JSON mode.
+func (out *Output) Println(a ...interface{}) {
+	if out.JSON {
+		return
+	}
+	if out.progress != nil {
+		out.progress.Printf("%s", fmt.Sprintln(a...))
+		return
+	}
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	fmt.Fprintln(out.writer, a...)
+}
+
+// Event emits a JSON event. It does nothing outside JSON mode.
+func (out *Output) Event(name string, fields Event) {
+	if !out.JSON {
+		return
+	}
+	event := Event{
+		"event": name,
+		"time":  time.Now().Format(time.RFC3339),
+	}
+	for key, value := range fields {
+		event[key] = value
+	}
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	json.NewEncoder(out.writer).Encode(event)
+}
+
+// jsonReporter emits progress events for a download
+type jsonReporter struct {
+	out *Output
+	ID  int
+}
+
+// Report emits a progress event. Throttling is left to the
+// WriteTracker.
+func (reporter *jsonReporter) Report(p writetracker.Progress) {
+	event := Event{
+		"id":          reporter.ID,
+		"bytes":       p.Bytes,
+		"rate":        int64(p.Rate()),
+		"done":        p.Done,
+		"elapsed_sec": p.Elapsed.Seconds(),
+	}
+	if p.Total > 0 {
+		event["total"] = p.Total
+		event["percent"] = p.Percent()
+	}
+	if eta := p.ETA(); eta >= 0 {
+		event["eta_sec"] = eta.Seconds()
+	}
+	reporter.out.Event(ProgressEvent, event)
+}
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutput(t *testing.T) {
+	t.Run("Human", func(t *testing.T) {
+		var buf bytes.Buffer
+		out := &Output{writer: &buf}
+		out.Printf("Downloading %s\n", "track")
+		out.Event(TrackStartedEvent, Event{"id": 1})
+		assert.Equal(t, "Downloading track\n", buf.String())
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		out := &Output{JSON: true, writer: &buf}
+		out.Printf("Downloading %s\n", "track")
+		out.Event(TrackStartedEvent, Event{"id": 1})
+		out.Event(SummaryEvent, Event{"items_failed": 0})
+
+		decoder := json.NewDecoder(&buf)
+		var event map[string]interface{}
+		assert.Equal(t, nil, decoder.Decode(&event))
+		assert.Equal(t, TrackStartedEvent, event["event"])
+		assert.Equal(t, float64(1), event["id"])
+		assert.Equal(t, nil, decoder.Decode(&event))
+		assert.Equal(t, SummaryEvent, event["event"])
+		assert.False(t, decoder.More(), "text should be suppressed")
+	})
+}
@@ -0,0 +1,219 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docopt/docopt-go"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultProfileName is the name of the profile created with a new
+// config
+const DefaultProfileName = "default"
+
+const (
+	DefaultTrackTemplate = `{{if .TrackNumber}}{{printf "%02d" .TrackNumber}} - {{end}}{{.Title}}`
+	DefaultAlbumTemplate = `{{.Album}}`
+)
+
+// Disc layouts, which decide how the tracks of albums with more than
+// one disc are told apart
+const (
+	// DiscLayoutPrefix prefixes filenames with the disc number, e.g.
+	// "1-01 - Title"
+	DiscLayoutPrefix = "prefix"
+	// DiscLayoutFolders puts each disc in a subfolder, e.g. "CD1"
+	DiscLayoutFolders = "folders"
+	// DiscLayoutNone leaves it to the track template, which can use
+	// .DiscNumber and .TotalDiscs
+	DiscLayoutNone = "none"
+)
+
+var ErrNoProfile = errors.New("profile does not exist")
+var ErrBadDiscLayout = errors.New("disc layout must be prefix, folders or none")
+
+// Profile stores the settings for one account
+type Profile struct {
+	DefaultFormat string `json:"default_format"`
+	OutputDir     string `json:"output_dir"`
+	TrackTemplate string `json:"track_template"`
+	AlbumTemplate string `json:"album_template"`
+	DiscLayout    string `json:"disc_layout,omitempty"`
+	LyricsFile    bool   `json:"lyrics_file,omitempty"`
+	EmbedLyrics   bool   `json:"embed_lyrics,omitempty"`
+	// ArtistSeparator joins the names of tags with more than one
+	// artist into a single value. If empty, each name is a separate
+	// value.
+	ArtistSeparator string `json:"artist_separator,omitempty"`
+}
+
+// NewProfile creates a profile with default settings
+func NewProfile() *Profile {
+	return &Profile{
+		DefaultFormat: "MP3_320",
+		OutputDir:     ".",
+		TrackTemplate: DefaultTrackTemplate,
+		AlbumTemplate: DefaultAlbumTemplate,
+	}
+}
+
+// GetDiscLayout returns the disc layout of the profile, which is
+// DiscLayoutPrefix if not set
+func (profile *Profile) GetDiscLayout() string {
+	if profile.DiscLayout == "" {
+		return DiscLayoutPrefix
+	}
+	return profile.DiscLayout
+}
+
+// joinArtists returns the values of a tag naming the given artists,
+// joined with the artist separator if there is one
+func (profile *Profile) joinArtists(names []string) []string {
+	if profile.ArtistSeparator == "" || len(names) < 2 {
+		return names
+	}
+	return []string{strings.Join(names, profile.ArtistSeparator)}
+}
+
+// checkDiscLayout checks that a disc layout is valid
+func checkDiscLayout(layout string) error {
+	switch layout {
+	case DiscLayoutPrefix, DiscLayoutFolders, DiscLayoutNone:
+		return nil
+	}
+	return ErrBadDiscLayout
+}
+
+// checkProfileName checks that a new profile would not share the
+// environment variable of an existing profile's arl cookie, which
+// happens for names that only differ in case or punctuation
+func (config *Configuration) checkProfileName(name string) error {
+	envStore := NewEnvSecretStore(secretEnvPrefix)
+	variable := envStore.VariableName(arlSecretName(name))
+	for existing := range config.Profiles {
+		if envStore.VariableName(arlSecretName(existing)) == variable {
+			return fmt.Errorf("profile %s would share the %s variable with profile %s", name, variable, existing)
+		}
+	}
+	return nil
+}
+
+// UseProfile selects a profile for this run only, without changing
+// the active profile saved in the config
+func (config *Configuration) UseProfile(name string) error {
+	if _, ok := config.Profiles[name]; !ok {
+		return fmt.Errorf("%s: %s", ErrNoProfile, name)
+	}
+	config.profileOverride = name
+	return nil
+}
+
+// ProfileName returns the name of the profile in use
+func (config *Configuration) ProfileName() string {
+	if config.profileOverride != "" {
+		return config.profileOverride
+	}
+	if config.ActiveProfile == "" {
+		return DefaultProfileName
+	}
+	return config.ActiveProfile
+}
+
+// Profile returns the profile in use. An error is returned if the
+// active profile has been removed from the config file by hand, as
+// changes to a stand-in profile could not be saved.
+func (config *Configuration) Profile() (*Profile, error) {
+	profile, ok := config.Profiles[config.ProfileName()]
+	if !ok {
+		return nil, fmt.Errorf("%s: %s", ErrNoProfile, config.ProfileName())
+	}
+	return profile, nil
+}
+
+// ProfileCommand handles the profile subcommands
+func ProfileCommand(opts docopt.Opts, config *Configuration) {
+	if list, _ := opts.Bool("list"); list {
+		profileList(config)
+		return
+	}
+
+	name, err := opts.String("<name>")
+	if err != nil {
+		logrus.Fatalf("failed to parse args: %s", err)
+	}
+
+	if use, _ := opts.Bool("use"); use {
+		if _, ok := config.Profiles[name]; !ok {
+			logrus.Fatalf("%s: %s", ErrNoProfile, name)
+		}
+		config.ActiveProfile = name
+		if err := config.SaveConfig(); err != nil {
+			logrus.Fatalf("failed to save config: %s", err)
+		}
+		fmt.Printf("Now using profile %s\n", name)
+		return
+	}
+
+	if add, _ := opts.Bool("add"); add {
+		if _, ok := config.Profiles[name]; ok {
+			logrus.Fatalf("profile %s already exists", name)
+		}
+		if err := config.checkProfileName(name); err != nil {
+			logrus.Fatal(err)
+		}
+		config.Profiles[name] = NewProfile()
+		if err := config.SaveConfig(); err != nil {
+			logrus.Fatalf("failed to save config: %s", err)
+		}
+		fmt.Printf("Added profile %s. Run \"deezerdl login --profile=%s\" to log in with it.\n", name, name)
+		return
+	}
+
+	if remove, _ := opts.Bool("remove"); remove {
+		if _, ok := config.Profiles[name]; !ok {
+			logrus.Fatalf("%s: %s", ErrNoProfile, name)
+		}
+		if name == config.ActiveProfile {
+			logrus.Fatalf("cannot remove the active profile -- switch to another profile first")
+		}
+		delete(config.Profiles, name)
+		if err := config.SaveConfig(); err != nil {
+			logrus.Fatalf("failed to save config: %s", err)
+		}
+
+		// forget the credentials too
+		if store, err := config.Secrets(); err != nil {
+			logrus.Warnf("failed to open secret store: %s", err)
+		} else if err := store.Delete(arlSecretName(name)); err != nil && err != ErrReadOnlySecrets {
+			logrus.Warnf("failed to remove arl cookie: %s", err)
+		}
+		if store, err := LoadSessionStore(); err != nil {
+			logrus.Warnf("failed to open session store: %s", err)
+		} else if err := store.Delete(name); err != nil {
+			logrus.Warnf("failed to remove session: %s", err)
+		}
+		fmt.Printf("Removed profile %s\n", name)
+		return
+	}
+}
+
+// profileList prints the names of all profiles, marking the active
+// one
+func profileList(config *Configuration) {
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		marker := " "
+		if name == config.ProfileName() {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, name)
+	}
+}
@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckProfileName(t *testing.T) {
+	config := NewConfiguration()
+	config.Profiles["a-b"] = NewProfile()
+	config.Profiles["Work"] = NewProfile()
+
+	assert.Equal(t, nil, config.checkProfileName("home"))
+	for _, name := range []string{"a_b", "A.B", "work", "WORK"} {
+		assert.NotEqual(t, nil, config.checkProfileName(name), name)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	config := NewConfiguration()
+	config.Profiles["work"] = NewProfile()
+
+	config.ActiveProfile = "work"
+	profile, err := config.Profile()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, config.Profiles["work"], profile)
+
+	// a stand-in for a missing profile would silently lose changes
+	config.ActiveProfile = "removed"
+	_, err = config.Profile()
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	humanize "github.com/dustin/go-humanize"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/joshbarrass/deezerdl/pkg/ratelimit"
+)
+
+// DefaultRateLimits is the number of requests per second made to each
+// endpoint unless the config says otherwise. The public API allows 50
+// requests every 5 seconds. Downloads from the CDN are not limited.
+var DefaultRateLimits = map[deezer.Endpoint]float64{
+	deezer.EndpointGateway: 5,
+	deezer.EndpointPublic:  10,
+	deezer.EndpointCDN:     0,
+}
+
+// rateLimitKeys maps the config keys of the rate limits to their
+// endpoints
+var rateLimitKeys = map[string]deezer.Endpoint{
+	"GatewayRateLimit": deezer.EndpointGateway,
+	"PublicRateLimit":  deezer.EndpointPublic,
+	"CDNRateLimit":     deezer.EndpointCDN,
+}
+
+// RateLimit returns the number of requests per second allowed to an
+// endpoint, or 0 for no limit
+func (config *Configuration) RateLimit(endpoint deezer.Endpoint) float64 {
+	if rate, ok := config.RateLimits[endpoint]; ok {
+		return rate
+	}
+	return DefaultRateLimits[endpoint]
+}
+
+// setRateLimit sets the rate limit of an endpoint from a config value
+func (config *Configuration) setRateLimit(endpoint deezer.Endpoint, value string) error {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(rate) || math.IsInf(rate, 0) || rate < 0 {
+		return fmt.Errorf("rate limit must be a number of requests per second, or 0 for no limit: %s", value)
+	}
+	if config.RateLimits == nil {
+		config.RateLimits = make(map[deezer.Endpoint]float64)
+	}
+	config.RateLimits[endpoint] = rate
+	return nil
+}
+
+// useRateLimits configures the api with a limiter for each endpoint
+func (config *Configuration) useRateLimits(api *deezer.API) {
+	for _, endpoint := range []deezer.Endpoint{deezer.EndpointGateway, deezer.EndpointPublic, deezer.EndpointCDN} {
+		rate := config.RateLimit(endpoint)
+		api.SetRateLimit(endpoint, ratelimit.NewLimiter(rate, ratelimit.DefaultBurst(rate)))
+	}
+}
+
+// ParseByteRate parses a download rate such as "2MiB/s" or "500k" into
+// bytes per second. "/s" is optional, and 0 or an empty string means
+// no limit.
+func ParseByteRate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, "/s")
+	if s == "" {
+		return 0, nil
+	}
+	rate, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: %s", s, err)
+	}
+	return float64(rate), nil
+}
+
+// newBandwidthLimiter creates the limiter shared by every download,
+// or nil for no limit
+func newBandwidthLimiter(rate float64) *ratelimit.Limiter {
+	return ratelimit.NewLimiter(rate, ratelimit.DefaultBurst(rate))
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	config := NewConfiguration()
+	assert.Equal(t, 5.0, config.RateLimit(deezer.EndpointGateway))
+	assert.Equal(t, 0.0, config.RateLimit(deezer.EndpointCDN))
+
+	assert.Equal(t, nil, config.setRateLimit(deezer.EndpointGateway, "0"))
+	assert.Equal(t, nil, config.setRateLimit(deezer.EndpointCDN, "2.5"))
+	assert.Equal(t, 0.0, config.RateLimit(deezer.EndpointGateway))
+	assert.Equal(t, 10.0, config.RateLimit(deezer.EndpointPublic))
+	assert.Equal(t, 2.5, config.RateLimit(deezer.EndpointCDN))
+
+	assert.NotEqual(t, nil, config.setRateLimit(deezer.EndpointCDN, "-1"))
+	assert.NotEqual(t, nil, config.setRateLimit(deezer.EndpointCDN, "fast"))
+	for _, value := range []string{"NaN", "Inf", "+Inf", "-Inf", "infinity"} {
+		assert.NotEqual(t, nil, config.setRateLimit(deezer.EndpointCDN, value), value)
+	}
+	assert.Equal(t, 2.5, config.RateLimit(deezer.EndpointCDN))
+}
+
+func TestParseByteRate(t *testing.T) {
+	tests := map[string]float64{
+		"2MiB/s": 2 * 1024 * 1024,
+		"500k":   500 * 1000,
+		"1 MB/s": 1000 * 1000,
+		"0":      0,
+		"":       0,
+	}
+	for s, expected := range tests {
+		rate, err := ParseByteRate(s)
+		assert.Equal(t, nil, err, s)
+		assert.Equal(t, expected, rate, s)
+	}
+
+	_, err := ParseByteRate("fast")
+	assert.NotEqual(t, nil, err)
+}
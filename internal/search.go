@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docopt/docopt-go"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrBadSelection is returned for a --select list that can't be parsed
+var ErrBadSelection = errors.New("selection must be numbers or ranges such as 1,3-5")
+
+// ErrArtistIDs is returned for --ids with an artist search, since
+// artists can't be downloaded
+var ErrArtistIDs = errors.New("--ids can't be used for artists, which can't be downloaded")
+
+// SearchResult is a single search result of any type, numbered by its
+// position in the whole result list
+type SearchResult struct {
+	Number  int         `json:"number"`
+	Type    string      `json:"type"`
+	ID      int         `json:"id"`
+	Title   string      `json:"title"`
+	Artist  string      `json:"artist,omitempty"`
+	Details string      `json:"details,omitempty"`
+	Link    string      `json:"link"`
+	Result  interface{} `json:"result"`
+}
+
+// searchTypes lists the types that can be searched for
+var searchTypes = []string{deezer.SearchTracks, deezer.SearchAlbums, deezer.SearchArtists, deezer.SearchPlaylists}
+
+// Search searches Deezer and prints the results
+func Search(opts docopt.Opts, config *Configuration) {
+	searchType := deezer.SearchTracks
+	for _, t := range searchTypes {
+		if selected, _ := opts.Bool(t); selected {
+			searchType = t
+		}
+	}
+	words, _ := opts["<query>"].([]string)
+	query := strings.Join(words, " ")
+
+	limit, err := positiveIntOpt(opts, "--limit")
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	page, err := positiveIntOpt(opts, "--page")
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	index := (page - 1) * limit
+	ids, _ := opts.Bool("--ids")
+	if ids && searchType == deezer.SearchArtists {
+		logrus.Fatal(ErrArtistIDs)
+	}
+
+	var selection map[int]bool
+	if list, err := opts.String("--select"); err == nil && list != "" {
+		selection, err = ParseSelection(list)
+		if err != nil {
+			logrus.Fatalf("invalid selection %q: %s", list, err)
+		}
+	}
+
+	api, err := deezer.NewAPI(false)
+	if err != nil {
+		logrus.Fatalf("failed to create API: %s", err)
+	}
+	config.useRateLimits(api)
+	results, total, err := search(api, searchType, query, index, limit)
+	if err != nil {
+		logrus.Fatalf("search failed: %s", err)
+	}
+	if selection != nil {
+		var selected []*SearchResult
+		for _, result := range results {
+			if selection[result.Number] {
+				selected = append(selected, result)
+			}
+		}
+		results = selected
+	}
+
+	if ids {
+		if err := printSearchIDs(os.Stdout, results); err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
+	if jsonMode, _ := opts.Bool("--json"); jsonMode {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, result := range results {
+			if err := encoder.Encode(result); err != nil {
+				logrus.Fatalf("failed to write JSON: %s", err)
+			}
+		}
+		return
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No results")
+		return
+	}
+	printSearchResults(os.Stdout, results)
+	fmt.Printf("\nShowing %d-%d of %d results\n", index+1, index+len(results), total)
+}
+
+// positiveIntOpt parses an option that must be a positive number
+func positiveIntOpt(opts docopt.Opts, name string) (int, error) {
+	s, err := opts.String(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive number, not %s", name, s)
+	}
+	return n, nil
+}
+
+// search runs a search and converts the results, numbering them from
+// index+1
+func search(api *deezer.API, searchType, query string, index, limit int) ([]*SearchResult, int, error) {
+	var results []*SearchResult
+	var total int
+	var err error
+	add := func(result *SearchResult) {
+		result.Number = index + len(results) + 1
+		result.Type = searchType
+		results = append(results, result)
+	}
+
+	switch searchType {
+	case deezer.SearchTracks:
+		var tracks []deezer.TrackResult
+		tracks, total, err = api.SearchTracks(query, index, limit)
+		for _, track := range tracks {
+			add(&SearchResult{
+				ID:      track.ID,
+				Title:   track.Title,
+				Artist:  track.Artist.Name,
+				Details: fmt.Sprintf("%s, %s", track.Album.Title, formatDuration(track.Duration)),
+				Link:    track.Link,
+				Result:  track,
+			})
+		}
+	case deezer.SearchAlbums:
+		var albums []deezer.AlbumResult
+		albums, total, err = api.SearchAlbums(query, index, limit)
+		for _, album := range albums {
+			add(&SearchResult{
+				ID:      album.ID,
+				Title:   album.Title,
+				Artist:  album.Artist.Name,
+				Details: fmt.Sprintf("%s, %d tracks", album.RecordType, album.NumTracks),
+				Link:    album.Link,
+				Result:  album,
+			})
+		}
+	case deezer.SearchArtists:
+		var artists []deezer.ArtistResult
+		artists, total, err = api.SearchArtists(query, index, limit)
+		for _, artist := range artists {
+			add(&SearchResult{
+				ID:      artist.ID,
+				Title:   artist.Name,
+				Details: fmt.Sprintf("%d albums, %s fans", artist.NumAlbums, humanize.Comma(int64(artist.NumFans))),
+				Link:    artist.Link,
+				Result:  artist,
+			})
+		}
+	case deezer.SearchPlaylists:
+		var playlists []deezer.PlaylistResult
+		playlists, total, err = api.SearchPlaylists(query, index, limit)
+		for _, playlist := range playlists {
+			add(&SearchResult{
+				ID:      playlist.ID,
+				Title:   playlist.Title,
+				Artist:  playlist.User.Name,
+				Details: fmt.Sprintf("%d tracks", playlist.NumTracks),
+				Link:    playlist.Link,
+				Result:  playlist,
+			})
+		}
+	default:
+		return nil, 0, fmt.Errorf("can't search for %s", searchType)
+	}
+	return results, total, err
+}
+
+// printSearchResults prints numbered search results as a table
+func printSearchResults(w io.Writer, results []*SearchResult) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, result := range results {
+		fmt.Fprintf(tw, "%d.\t%s\t%s\t%s\t%d\n", result.Number, result.Title, result.Artist, result.Details, result.ID)
+	}
+	tw.Flush()
+}
+
+// printSearchIDs prints search results as "<type> <ID>" lines, which
+// can be read by download --from-file. Nothing is printed if any
+// result can't be downloaded.
+func printSearchIDs(w io.Writer, results []*SearchResult) error {
+	for _, result := range results {
+		if !isItemType(result.Type) {
+			return ErrArtistIDs
+		}
+	}
+	for _, result := range results {
+		fmt.Fprintf(w, "%s %d\n", result.Type, result.ID)
+	}
+	return nil
+}
+
+// ParseSelection parses a list of result numbers such as "1,3-5"
+func ParseSelection(list string) (map[int]bool, error) {
+	selection := make(map[int]bool)
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 1 {
+			return nil, ErrBadSelection
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first {
+				return nil, ErrBadSelection
+			}
+		}
+		for n := first; n <= last; n++ {
+			selection[n] = true
+		}
+	}
+	return selection, nil
+}
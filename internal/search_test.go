@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSelection(t *testing.T) {
+	selection, err := ParseSelection("1, 3-5")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[int]bool{1: true, 3: true, 4: true, 5: true}, selection)
+
+	for _, bad := range []string{"", "0", "a", "5-3", "1,,2"} {
+		_, err := ParseSelection(bad)
+		assert.Equal(t, ErrBadSelection, err, bad)
+	}
+}
+
+func TestPrintSearchIDs(t *testing.T) {
+	results := []*SearchResult{
+		{Number: 1, Type: AlbumItem, ID: 302127},
+		{Number: 2, Type: AlbumItem, ID: 6575789},
+	}
+	var buf bytes.Buffer
+	assert.Equal(t, nil, printSearchIDs(&buf, results))
+	assert.Equal(t, "album 302127\nalbum 6575789\n", buf.String())
+
+	// the output is a valid batch file
+	items, err := ReadItems(&buf)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, 6575789, items[1].ID)
+}
+
+func TestPrintSearchIDsTypes(t *testing.T) {
+	// every type that can be searched for either reads back as a batch
+	// file or is refused
+	for i, searchType := range searchTypes {
+		results := []*SearchResult{{Number: 1, Type: searchType, ID: i + 1}}
+		var buf bytes.Buffer
+		err := printSearchIDs(&buf, results)
+		if searchType == ArtistType {
+			assert.Equal(t, ErrArtistIDs, err)
+			assert.Equal(t, "", buf.String())
+			continue
+		}
+		assert.Equal(t, nil, err, searchType)
+		items, err := ReadItems(&buf)
+		assert.Equal(t, nil, err, searchType)
+		assert.Equal(t, []*Item{{Type: searchType, ID: i + 1, Line: 1}}, items)
+	}
+}
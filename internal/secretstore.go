@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	secretsFile                  = "credentials.json"
+	secretsFilePerms os.FileMode = 0600
+	secretEnvPrefix              = "DEEZERDL_"
+)
+
+// Names of the available secret store backends
+const (
+	FileSecretStoreName = "file"
+	EnvSecretStoreName  = "env"
+)
+
+// arlSecretName returns the name the ARL cookie of a profile is
+// stored under. The default profile uses plain "arl" so that its
+// environment variable is DEEZERDL_ARL.
+func arlSecretName(profile string) string {
+	if profile == DefaultProfileName {
+		return "arl"
+	}
+	return "arl_" + profile
+}
+
+var (
+	ErrSecretNotFound     = errors.New("secret not found")
+	ErrReadOnlySecrets    = errors.New("secret store is read-only")
+	ErrInsecureSecretFile = errors.New("credentials file is readable by other users -- run chmod 600 on it")
+)
+
+// SecretStore is a place where credentials can be kept outside of the
+// main configuration file
+type SecretStore interface {
+	// Get returns the named secret, or ErrSecretNotFound
+	Get(name string) (string, error)
+	// Set stores the named secret
+	Set(name, value string) error
+	// Delete removes the named secret. Deleting a secret that does
+	// not exist is not an error.
+	Delete(name string) error
+}
+
+// NewSecretStore returns the secret store backend with the given
+// name. An empty name selects the file backend.
+func NewSecretStore(name string) (SecretStore, error) {
+	switch name {
+	case FileSecretStoreName, "":
+		path, err := getSecretsPath()
+		if err != nil {
+			return nil, err
+		}
+		return NewFileSecretStore(path), nil
+	case EnvSecretStoreName:
+		return NewEnvSecretStore(secretEnvPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown secret store: %s", name)
+	}
+}
+
+// getSecretsPath returns the path of the credentials file in the
+// config dir
+func getSecretsPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(os.ExpandEnv(configDir), secretsFile), nil
+}
+
+// FileSecretStore keeps secrets in a JSON file that only the current
+// user may read
+type FileSecretStore struct {
+	Path string
+}
+
+// NewFileSecretStore creates a FileSecretStore backed by the file at
+// path. The file is created when the first secret is set.
+func NewFileSecretStore(path string) *FileSecretStore {
+	return &FileSecretStore{
+		Path: path,
+	}
+}
+
+// load reads all secrets from the file, refusing to do so if the file
+// is group or world readable
+func (store *FileSecretStore) load() (map[string]string, error) {
+	secrets := make(map[string]string)
+
+	inFile, err := os.Open(store.Path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer inFile.Close()
+
+	// permission bits are not meaningful on windows
+	if runtime.GOOS != "windows" {
+		info, err := inFile.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode().Perm()&0077 != 0 {
+			return nil, ErrInsecureSecretFile
+		}
+	}
+
+	if err := json.NewDecoder(inFile).Decode(&secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// save writes all secrets to the file
+func (store *FileSecretStore) save(secrets map[string]string) error {
+	outFile, err := os.OpenFile(store.Path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, secretsFilePerms)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	// OpenFile does not change the mode of an existing file
+	if err := outFile.Chmod(secretsFilePerms); err != nil && runtime.GOOS != "windows" {
+		return err
+	}
+
+	encoder := json.NewEncoder(outFile)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(secrets)
+}
+
+// Get returns the named secret
+func (store *FileSecretStore) Get(name string) (string, error) {
+	secrets, err := store.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[name]
+	if !ok || value == "" {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// Set stores the named secret
+func (store *FileSecretStore) Set(name, value string) error {
+	secrets, err := store.load()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	return store.save(secrets)
+}
+
+// Delete removes the named secret
+func (store *FileSecretStore) Delete(name string) error {
+	secrets, err := store.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return nil
+	}
+	delete(secrets, name)
+	return store.save(secrets)
+}
+
+// EnvSecretStore reads secrets from environment variables. The
+// variable for a secret is the prefix followed by the upper-cased
+// name, e.g. DEEZERDL_ARL. It cannot be written to.
+type EnvSecretStore struct {
+	Prefix string
+}
+
+// NewEnvSecretStore creates an EnvSecretStore using the given
+// variable prefix
+func NewEnvSecretStore(prefix string) *EnvSecretStore {
+	return &EnvSecretStore{
+		Prefix: prefix,
+	}
+}
+
+// VariableName returns the environment variable used for the named
+// secret
+func (store *EnvSecretStore) VariableName(name string) string {
+	name = strings.ToUpper(name)
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	return store.Prefix + name
+}
+
+// Get returns the named secret
+func (store *EnvSecretStore) Get(name string) (string, error) {
+	value := os.Getenv(store.VariableName(name))
+	if value == "" {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// Set always fails, since the environment cannot be changed for
+// future runs
+func (store *EnvSecretStore) Set(name, value string) error {
+	return ErrReadOnlySecrets
+}
+
+// Delete always fails, since the environment cannot be changed for
+// future runs
+func (store *EnvSecretStore) Delete(name string) error {
+	return ErrReadOnlySecrets
+}
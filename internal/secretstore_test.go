@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSecretStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deezerdl")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, secretsFile)
+	store := NewFileSecretStore(path)
+
+	t.Run("Missing Secret", func(t *testing.T) {
+		_, err := store.Get("arl")
+		assert.Equal(t, ErrSecretNotFound, err)
+	})
+
+	t.Run("Set And Get", func(t *testing.T) {
+		assert.Equal(t, nil, store.Set("arl", "abc123"))
+		value, err := store.Get("arl")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "abc123", value)
+
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, secretsFilePerms, info.Mode().Perm())
+		}
+	})
+
+	t.Run("Refuse Insecure File", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("permissions are not checked on windows")
+		}
+		assert.Equal(t, nil, os.Chmod(path, 0644))
+		_, err := store.Get("arl")
+		assert.Equal(t, ErrInsecureSecretFile, err)
+		assert.Equal(t, nil, os.Chmod(path, secretsFilePerms))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.Equal(t, nil, store.Delete("arl"))
+		_, err := store.Get("arl")
+		assert.Equal(t, ErrSecretNotFound, err)
+	})
+}
+
+func TestEnvSecretStore(t *testing.T) {
+	store := NewEnvSecretStore(secretEnvPrefix)
+	assert.Equal(t, "DEEZERDL_ARL", store.VariableName("arl"))
+
+	os.Setenv("DEEZERDL_ARL", "abc123")
+	defer os.Unsetenv("DEEZERDL_ARL")
+	value, err := store.Get("arl")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "abc123", value)
+
+	assert.Equal(t, ErrReadOnlySecrets, store.Set("arl", "x"))
+}
+
+func TestMigrateARLToEnv(t *testing.T) {
+	config := &Configuration{SecretStore: EnvSecretStoreName, ARLCookie: "legacy"}
+
+	// the env store can't be written to, so the plaintext cookie is
+	// kept and still used
+	migrated, err := config.migrateARL()
+	assert.Equal(t, nil, err)
+	assert.False(t, migrated)
+	arl, err := config.GetARL()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "legacy", arl)
+
+	// once the variable is set it can be dropped
+	os.Setenv("DEEZERDL_ARL", "abc123")
+	defer os.Unsetenv("DEEZERDL_ARL")
+	migrated, err = config.migrateARL()
+	assert.Equal(t, nil, err)
+	assert.True(t, migrated)
+}
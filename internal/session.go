@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+)
+
+const sessionsFile = "sessions.json"
+
+// SessionStore keeps the deezer session of each profile between runs,
+// so that the cookies and token do not have to be fetched every time.
+// Sessions hold the sid cookie and token, so the file is only readable
+// by the current user. The arl cookie stays in the secret store.
+type SessionStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewSessionStore creates a SessionStore backed by the file at path
+func NewSessionStore(path string) *SessionStore {
+	return &SessionStore{
+		Path: path,
+	}
+}
+
+// LoadSessionStore opens the session store in the config dir
+func LoadSessionStore() (*SessionStore, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewSessionStore(filepath.Join(os.ExpandEnv(configDir), sessionsFile)), nil
+}
+
+// load reads all sessions from the file
+func (store *SessionStore) load() (map[string]*deezer.Session, error) {
+	sessions := make(map[string]*deezer.Session)
+
+	inFile, err := os.Open(store.Path)
+	if os.IsNotExist(err) {
+		return sessions, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer inFile.Close()
+
+	if err := json.NewDecoder(inFile).Decode(&sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// save writes all sessions to the file. They are written to a
+// temporary file first, so that another run reading or writing the
+// file at the same time never sees it half-written.
+func (store *SessionStore) save(sessions map[string]*deezer.Session) error {
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(store.Path, append(data, '\n'), secretsFilePerms)
+}
+
+// Get returns the saved session of a profile, or nil if there is none
+func (store *SessionStore) Get(profile string) (*deezer.Session, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	sessions, err := store.load()
+	if err != nil {
+		return nil, err
+	}
+	return sessions[profile], nil
+}
+
+// Set saves the session of a profile
+func (store *SessionStore) Set(profile string, session *deezer.Session) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	sessions, err := store.load()
+	if err != nil {
+		return err
+	}
+	sessions[profile] = session
+	return store.save(sessions)
+}
+
+// Delete forgets the session of a profile
+func (store *SessionStore) Delete(profile string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	sessions, err := store.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := sessions[profile]; !ok {
+		return nil
+	}
+	delete(sessions, profile)
+	return store.save(sessions)
+}
+
+// Update saves a new session of a profile, or forgets the profile's
+// session if the arl cookie was rejected
+func (store *SessionStore) Update(profile string, session *deezer.Session) error {
+	if !session.LoggedIn() {
+		return store.Delete(profile)
+	}
+	return store.Set(profile, session)
+}
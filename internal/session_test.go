@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deezerdl")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, sessionsFile)
+	store := NewSessionStore(path)
+
+	session, err := store.Get(DefaultProfileName)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, (*deezer.Session)(nil), session)
+
+	saved := &deezer.Session{
+		ARLHash: deezer.HashARL("arl"),
+		SID:     "sid",
+		Token:   "token",
+		User:    &deezer.User{ID: 1234, Name: "someone"},
+		Expires: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	assert.Equal(t, nil, store.Update(DefaultProfileName, saved))
+	session, err = store.Get(DefaultProfileName)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, saved, session)
+
+	// the arl cookie itself is never written
+	data, err := ioutil.ReadFile(path)
+	assert.Equal(t, nil, err)
+	assert.False(t, strings.Contains(string(data), `"arl"`))
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, secretsFilePerms, info.Mode().Perm())
+	}
+
+	// a session whose arl was rejected is forgotten
+	assert.Equal(t, nil, store.Update(DefaultProfileName, &deezer.Session{User: &deezer.User{}}))
+	session, err = store.Get(DefaultProfileName)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, (*deezer.Session)(nil), session)
+}
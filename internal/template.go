@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+)
+
+// TemplateData is the data available to the track and album filename
+// templates
+type TemplateData struct {
+	ID          int
+	Title       string
+	TrackNumber int
+	DiscNumber  int
+	TotalDiscs  int
+	Album       string
+	AlbumID     int
+	Playlist    string
+	Format      string
+}
+
+// NewTemplateData fills in the template data for a track. album may
+// be nil for standalone tracks, and trackNumber may be 0 if it is
+// unknown.
+func NewTemplateData(track *deezer.Track, album *deezer.Album, trackNumber int, format deezer.Format) *TemplateData {
+	data := TemplateData{
+		ID:          track.ID,
+		Title:       track.Title,
+		TrackNumber: trackNumber,
+		Format:      FormatToFormatString(format),
+	}
+	if album != nil {
+		data.Album = album.Title
+		data.AlbumID = album.ID
+	}
+	return &data
+}
+
+// NewAlbumTemplateData fills in the template data for an album
+func NewAlbumTemplateData(album *deezer.Album) *TemplateData {
+	return &TemplateData{
+		Title:   album.Title,
+		Album:   album.Title,
+		AlbumID: album.ID,
+	}
+}
+
+// NewPlaylistTemplateData fills in the template data for a playlist.
+// Playlists use the album template for their directory, so the title
+// is also given as the album.
+func NewPlaylistTemplateData(playlist *deezer.Playlist) *TemplateData {
+	return &TemplateData{
+		Title:    playlist.Title,
+		Album:    playlist.Title,
+		Playlist: playlist.Title,
+	}
+}
+
+// RenderTemplate executes a filename template
+func RenderTemplate(text string, data *TemplateData) (string, error) {
+	tmpl, err := template.New("filename").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateFilename(t *testing.T) {
+	track := &deezer.Track{
+		ID:    3135553,
+		Title: "One More Time",
+	}
+
+	t.Run("Standalone Track", func(t *testing.T) {
+		filename, err := CalculateFilename(DefaultTrackTemplate, NewTemplateData(track, nil, 0, deezer.FLAC), deezer.FLAC)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "One More Time.flac", filename)
+	})
+
+	t.Run("Album Track", func(t *testing.T) {
+		filename, err := CalculateFilename(DefaultTrackTemplate, NewTemplateData(track, nil, 1, deezer.MP3_320), deezer.MP3_320)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "01 - One More Time.mp3", filename)
+	})
+
+	t.Run("Custom Template", func(t *testing.T) {
+		filename, err := CalculateFilename("{{.ID}} {{.Format}}/{{.Title}}", NewTemplateData(track, nil, 0, deezer.FLAC), deezer.FLAC)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "3135553 FLAC-One More Time.flac", filename)
+	})
+}
@@ -0,0 +1,363 @@
+// Package cache keeps deezer metadata responses on disk so that they
+// do not have to be fetched again on the next run
+package cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+)
+
+const (
+	dirPerms  os.FileMode = 0755
+	filePerms os.FileMode = 0644
+	entryExt              = ".json"
+)
+
+// DefaultTTLs is how long responses from each method are kept for.
+// Playlists are edited often, while songs and albums rarely change.
+var DefaultTTLs = map[string]time.Duration{
+	deezer.CacheSong:     7 * 24 * time.Hour,
+	deezer.CacheAlbum:    7 * 24 * time.Hour,
+	deezer.CacheArtist:   24 * time.Hour,
+	deezer.CachePlaylist: time.Hour,
+}
+
+// DefaultAccountMethods lists the methods whose responses depend on
+// the account they were fetched with. Song data holds the rights and
+// file sizes of the account, so it is not shared between profiles.
+var DefaultAccountMethods = map[string]bool{
+	deezer.CacheSong: true,
+}
+
+// DefaultMaxSize is the default limit on the size of the cache in
+// bytes
+const DefaultMaxSize = 100 * 1024 * 1024
+
+// trimRatio is how full the cache is left after trimming, so that it
+// is not trimmed again on the next few writes
+const trimRatio = 0.9
+
+// entry is a cached response as it is stored on disk
+type entry struct {
+	Stored  time.Time       `json:"stored"`
+	Expires time.Time       `json:"expires"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// DiskCache is a deezer.Cache that stores each response in its own
+// file, in a directory for each method. When the cache grows past
+// MaxSize, expired entries and then the oldest entries are removed
+// until it is back under 90% of MaxSize. The size of the cache is
+// counted once and then kept up to date as entries are written.
+type DiskCache struct {
+	Dir string
+	// TTLs is how long responses from each method are kept for.
+	// Responses from other methods are not cached.
+	TTLs map[string]time.Duration
+	// MaxSize is the limit on the total size of the entries in
+	// bytes, or 0 for no limit
+	MaxSize int64
+	// Profile keeps the entries of AccountMethods apart from those of
+	// other profiles
+	Profile        string
+	AccountMethods map[string]bool
+
+	now func() time.Time
+	// size is the total size of the entries, if sized is set
+	size  int64
+	sized bool
+	mu    sync.Mutex
+}
+
+// NewDiskCache creates a DiskCache in dir with the default TTLs and
+// size limit
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{
+		Dir:            dir,
+		TTLs:           DefaultTTLs,
+		MaxSize:        DefaultMaxSize,
+		AccountMethods: DefaultAccountMethods,
+		now:            time.Now,
+	}
+}
+
+// path returns the path of the entry for method and ID. Entries of
+// AccountMethods are kept in a directory for the profile.
+func (cache *DiskCache) path(method string, ID int) string {
+	dir := filepath.Join(cache.Dir, method)
+	if cache.AccountMethods[method] {
+		dir = filepath.Join(dir, url.PathEscape(cache.Profile))
+	}
+	return filepath.Join(dir, strconv.Itoa(ID)+entryExt)
+}
+
+// Get returns the cached data, or false if there is none or it has
+// expired
+func (cache *DiskCache) Get(method string, ID int) ([]byte, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	path := cache.path(method, ID)
+	e, err := readEntry(path)
+	if err != nil {
+		return nil, false
+	}
+	if !cache.now().Before(e.Expires) {
+		cache.remove(path)
+		return nil, false
+	}
+	return e.Data, true
+}
+
+// Set stores data in the cache, then trims the cache to MaxSize
+func (cache *DiskCache) Set(method string, ID int, data []byte) error {
+	ttl := cache.TTLs[method]
+	if ttl <= 0 {
+		return nil
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := cache.now()
+	encoded, err := json.Marshal(entry{
+		Stored:  now,
+		Expires: now.Add(ttl),
+		Data:    data,
+	})
+	if err != nil {
+		return err
+	}
+	path := cache.path(method, ID)
+	if err := os.MkdirAll(filepath.Dir(path), dirPerms); err != nil {
+		return err
+	}
+	if err := cache.countSize(); err != nil {
+		return err
+	}
+	var oldSize int64
+	if info, err := os.Stat(path); err == nil {
+		oldSize = info.Size()
+	}
+	// write to a temporary file first so that readers never see part
+	// of an entry
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, encoded, filePerms); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	cache.size += int64(len(encoded)) - oldSize
+	if cache.MaxSize > 0 && cache.size > cache.MaxSize {
+		return cache.trim()
+	}
+	return nil
+}
+
+// countSize counts the size of the entries if it is not known yet.
+// cache.mu must be held.
+func (cache *DiskCache) countSize() error {
+	if cache.sized {
+		return nil
+	}
+	files, err := cache.walk()
+	if err != nil {
+		return err
+	}
+	cache.size = 0
+	for _, file := range files {
+		cache.size += file.Size
+	}
+	cache.sized = true
+	return nil
+}
+
+// remove deletes the entry in the file at path. cache.mu must be held.
+func (cache *DiskCache) remove(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if cache.sized {
+		cache.size -= info.Size()
+	}
+	return nil
+}
+
+// Delete removes the cached data for method and ID, if there is any
+func (cache *DiskCache) Delete(method string, ID int) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.remove(cache.path(method, ID))
+}
+
+// readEntry reads the entry in the file at path
+func readEntry(path string) (*entry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// entryFile is an entry found while walking the cache
+type entryFile struct {
+	Method string
+	Path   string
+	Size   int64
+}
+
+// walk lists every entry in the cache
+func (cache *DiskCache) walk() ([]entryFile, error) {
+	var files []entryFile
+	err := filepath.Walk(cache.Dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, entryExt) {
+			return nil
+		}
+		// the method is the first directory, whatever is below it
+		rel, err := filepath.Rel(cache.Dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, entryFile{
+			Method: strings.SplitN(filepath.ToSlash(rel), "/", 2)[0],
+			Path:   path,
+			Size:   info.Size(),
+		})
+		return nil
+	})
+	return files, err
+}
+
+// trim removes entries until the cache is back under trimRatio of
+// MaxSize, starting with the expired ones and then the oldest. The
+// size is counted again, since other runs may have written entries.
+// cache.mu must be held.
+func (cache *DiskCache) trim() error {
+	files, err := cache.walk()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, file := range files {
+		total += file.Size
+	}
+	cache.size, cache.sized = total, true
+	if total <= cache.MaxSize {
+		return nil
+	}
+	target := int64(float64(cache.MaxSize) * trimRatio)
+
+	// unreadable entries are treated as expired
+	now := cache.now()
+	expired := make(map[string]bool)
+	stored := make(map[string]time.Time)
+	for _, file := range files {
+		e, err := readEntry(file.Path)
+		if err != nil || !now.Before(e.Expires) {
+			expired[file.Path] = true
+		} else {
+			stored[file.Path] = e.Stored
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if expired[files[i].Path] != expired[files[j].Path] {
+			return expired[files[i].Path]
+		}
+		return stored[files[i].Path].Before(stored[files[j].Path])
+	})
+	for _, file := range files {
+		if cache.size <= target {
+			break
+		}
+		if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		cache.size -= file.Size
+	}
+	return nil
+}
+
+// MethodStats describes the entries of one method in the cache
+type MethodStats struct {
+	Method  string `json:"method"`
+	Entries int    `json:"entries"`
+	Expired int    `json:"expired"`
+	Size    int64  `json:"size"`
+}
+
+// Stats describes what is in the cache
+type Stats struct {
+	Methods []MethodStats `json:"methods"`
+	Entries int           `json:"entries"`
+	Size    int64         `json:"size"`
+}
+
+// Stats counts the entries in the cache, sorted by method
+func (cache *DiskCache) Stats() (*Stats, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	files, err := cache.walk()
+	if err != nil {
+		return nil, err
+	}
+	now := cache.now()
+	byMethod := make(map[string]*MethodStats)
+	stats := &Stats{}
+	for _, file := range files {
+		methodStats, ok := byMethod[file.Method]
+		if !ok {
+			methodStats = &MethodStats{Method: file.Method}
+			byMethod[file.Method] = methodStats
+		}
+		methodStats.Entries++
+		methodStats.Size += file.Size
+		if e, err := readEntry(file.Path); err != nil || !now.Before(e.Expires) {
+			methodStats.Expired++
+		}
+		stats.Entries++
+		stats.Size += file.Size
+	}
+	for _, methodStats := range byMethod {
+		stats.Methods = append(stats.Methods, *methodStats)
+	}
+	sort.Slice(stats.Methods, func(i, j int) bool {
+		return stats.Methods[i].Method < stats.Methods[j].Method
+	})
+	return stats, nil
+}
+
+// Clear removes every entry from the cache
+func (cache *DiskCache) Clear() error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.size, cache.sized = 0, true
+	return os.RemoveAll(cache.Dir)
+}
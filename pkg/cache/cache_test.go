@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joshbarrass/deezerdl/pkg/deezer"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCache creates a cache in a temporary directory whose clock
+// only moves when the returned function is called. Remove cache.Dir
+// when done.
+func newTestCache(t *testing.T) (*DiskCache, func(time.Duration)) {
+	dir, err := ioutil.TempDir("", "deezerdl")
+	assert.Equal(t, nil, err)
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewDiskCache(dir)
+	cache.now = func() time.Time { return now }
+	return cache, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestDiskCache(t *testing.T) {
+	cache, advance := newTestCache(t)
+	defer os.RemoveAll(cache.Dir)
+
+	_, ok := cache.Get(deezer.CacheSong, 1)
+	assert.False(t, ok)
+
+	assert.Equal(t, nil, cache.Set(deezer.CacheSong, 1, []byte(`{"SNG_ID":"1"}`)))
+	assert.Equal(t, nil, cache.Set(deezer.CachePlaylist, 1, []byte(`{"id":1}`)))
+	data, ok := cache.Get(deezer.CacheSong, 1)
+	assert.True(t, ok)
+	assert.Equal(t, `{"SNG_ID":"1"}`, string(data))
+
+	// each method has its own TTL
+	advance(2 * time.Hour)
+	_, ok = cache.Get(deezer.CachePlaylist, 1)
+	assert.False(t, ok, "the playlist should have expired")
+	_, ok = cache.Get(deezer.CacheSong, 1)
+	assert.True(t, ok, "the song should not have expired")
+
+	// methods without a TTL are not cached
+	assert.Equal(t, nil, cache.Set("other", 1, []byte(`{}`)))
+	_, ok = cache.Get("other", 1)
+	assert.False(t, ok)
+
+	assert.Equal(t, nil, cache.Clear())
+	_, ok = cache.Get(deezer.CacheSong, 1)
+	assert.False(t, ok)
+}
+
+func TestDiskCacheMaxSize(t *testing.T) {
+	cache, advance := newTestCache(t)
+	defer os.RemoveAll(cache.Dir)
+	data := []byte(`{"SNG_ID":"1"}`)
+	assert.Equal(t, nil, cache.Set(deezer.CacheSong, 1, data))
+	stats, err := cache.Stats()
+	assert.Equal(t, nil, err)
+	entrySize := stats.Size
+
+	// room for two entries, with space to spare after trimming
+	cache.MaxSize = 2*entrySize + entrySize/2
+	advance(time.Minute)
+	assert.Equal(t, nil, cache.Set(deezer.CacheSong, 2, data))
+	advance(time.Minute)
+	assert.Equal(t, nil, cache.Set(deezer.CacheSong, 3, data))
+
+	// the oldest entry is removed to make room
+	_, ok := cache.Get(deezer.CacheSong, 1)
+	assert.False(t, ok)
+	_, ok = cache.Get(deezer.CacheSong, 2)
+	assert.True(t, ok)
+	_, ok = cache.Get(deezer.CacheSong, 3)
+	assert.True(t, ok)
+
+	// the running size matches what is on disk
+	stats, err = cache.Stats()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, stats.Size, cache.size)
+	assert.True(t, cache.size <= int64(float64(cache.MaxSize)*trimRatio))
+
+	// expired entries removed by Get are counted too
+	advance(8 * 24 * time.Hour)
+	_, ok = cache.Get(deezer.CacheSong, 2)
+	assert.False(t, ok)
+	stats, err = cache.Stats()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, stats.Size, cache.size)
+}
+
+func TestDiskCacheStats(t *testing.T) {
+	cache, advance := newTestCache(t)
+	defer os.RemoveAll(cache.Dir)
+	assert.Equal(t, nil, cache.Set(deezer.CacheSong, 1, []byte(`{}`)))
+	assert.Equal(t, nil, cache.Set(deezer.CacheSong, 2, []byte(`{}`)))
+	assert.Equal(t, nil, cache.Set(deezer.CachePlaylist, 1, []byte(`{}`)))
+	advance(2 * time.Hour)
+
+	stats, err := cache.Stats()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, stats.Entries)
+	assert.Equal(t, 2, len(stats.Methods))
+	assert.Equal(t, deezer.CachePlaylist, stats.Methods[0].Method)
+	assert.Equal(t, 1, stats.Methods[0].Entries)
+	assert.Equal(t, 1, stats.Methods[0].Expired)
+	assert.Equal(t, deezer.CacheSong, stats.Methods[1].Method)
+	assert.Equal(t, 2, stats.Methods[1].Entries)
+	assert.Equal(t, 0, stats.Methods[1].Expired)
+	assert.Equal(t, stats.Methods[0].Size+stats.Methods[1].Size, stats.Size)
+}
+
+func TestDiskCacheProfiles(t *testing.T) {
+	cache, _ := newTestCache(t)
+	defer os.RemoveAll(cache.Dir)
+	other := NewDiskCache(cache.Dir)
+	other.now = cache.now
+	cache.Profile, other.Profile = "default", "work"
+
+	// song data depends on the account, but playlists don't
+	assert.Equal(t, nil, cache.Set(deezer.CacheSong, 1, []byte(`{}`)))
+	assert.Equal(t, nil, cache.Set(deezer.CachePlaylist, 1, []byte(`{}`)))
+	_, ok := other.Get(deezer.CacheSong, 1)
+	assert.False(t, ok, "songs should not be shared between profiles")
+	_, ok = other.Get(deezer.CachePlaylist, 1)
+	assert.True(t, ok)
+
+	stats, err := cache.Stats()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, len(stats.Methods))
+	assert.Equal(t, deezer.CacheSong, stats.Methods[1].Method)
+
+	assert.Equal(t, nil, cache.Delete(deezer.CacheSong, 1))
+	_, ok = cache.Get(deezer.CacheSong, 1)
+	assert.False(t, ok)
+	assert.Equal(t, nil, cache.Delete(deezer.CacheSong, 1), "deleting a missing entry is not an error")
+}
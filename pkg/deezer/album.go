@@ -3,42 +3,82 @@ package deezer
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"strings"
 	"time"
 )
 
 const AlbumAPIFormat = "https://api.deezer.com/album/%d"
 
 type AlbumTrack struct {
-	ID    int    `json:"id"`
-	Title string `json:"title"`
-	Link  string `json:"link"`
+	ID       int         `json:"id"`
+	Title    string      `json:"title"`
+	Link     string      `json:"link"`
+	Duration int         `json:"duration"`
+	Explicit bool        `json:"explicit_lyrics"`
+	Artist   Contributor `json:"artist"`
+}
+
+// Contributor is an artist credited on an album or track. Role is
+// only set in the contributors list, e.g. "Main" or "Featured".
+type Contributor struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Link    string `json:"link"`
+	Picture string `json:"picture_xl"`
+	Role    string `json:"role"`
+}
+
+// Genre is a genre an album belongs to
+type Genre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
 }
 
 // AlbumResponse is an intermediate format for getting album data that
 // stores the data before putting it in an Album struct
 type AlbumResponse struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
-	Link        string `json:"link"`
-	CoverURL    string `json:"cover"`
-	CoverSmall  string `json:"cover_small"`
-	CoverMedium string `json:"cover_medium"`
-	CoverBig    string `json:"cover_big"`
-	CoverXL     string `json:"cover_xl"`
-	Date        string `json:"release_date"`
-	Tracks      struct {
-		Data []AlbumTrack `json:"data"`
-	} `json:tracks"`
+	ID           int           `json:"id"`
+	Title        string        `json:"title"`
+	Link         string        `json:"link"`
+	UPC          string        `json:"upc"`
+	CoverURL     string        `json:"cover"`
+	CoverSmall   string        `json:"cover_small"`
+	CoverMedium  string        `json:"cover_medium"`
+	CoverBig     string        `json:"cover_big"`
+	CoverXL      string        `json:"cover_xl"`
+	Label        string        `json:"label"`
+	NumTracks    int           `json:"nb_tracks"`
+	Duration     int           `json:"duration"`
+	Date         string        `json:"release_date"`
+	RecordType   string        `json:"record_type"`
+	Explicit     bool          `json:"explicit_lyrics"`
+	Artist       Contributor   `json:"artist"`
+	Contributors []Contributor `json:"contributors"`
+	Genres       struct {
+		Data []Genre `json:"data"`
+	} `json:"genres"`
+	Tracks trackPage       `json:"tracks"`
+	Error  *PublicAPIError `json:"error"`
 }
 
 // Album stores the data for the album of interest
 type Album struct {
-	ID        int
-	Title     string
-	Link      string
-	CoverURL  string
-	Covers    Covers
+	ID           int
+	Title        string
+	Link         string
+	UPC          string
+	Artist       Contributor
+	Contributors []Contributor
+	Genres       []Genre
+	Label        string
+	NumTracks    int
+	// Duration is the total length of the album in seconds
+	Duration   int
+	RecordType string
+	Explicit   bool
+	CoverURL   string
+	Covers     Covers
+	// Date is the release date, or the zero time if it is unknown
 	Date      time.Time
 	Tracklist []AlbumTrack
 	Tracks    []*Track
@@ -55,15 +95,29 @@ type Covers struct {
 
 // NewAlbum create an Album from an AlbumResponse
 func NewAlbum(response *AlbumResponse, api *API) (*Album, error) {
-	date, err := time.Parse("2006-01-02", response.Date)
-	if err != nil {
-		return nil, err
+	var date time.Time
+	// unknown dates are sent as 0000-00-00
+	if response.Date != "" && !strings.HasPrefix(response.Date, "0000") {
+		var err error
+		date, err = time.Parse("2006-01-02", response.Date)
+		if err != nil {
+			return nil, err
+		}
 	}
 	album := Album{
-		ID:       response.ID,
-		Title:    response.Title,
-		Link:     response.Link,
-		CoverURL: response.CoverURL,
+		ID:           response.ID,
+		Title:        response.Title,
+		Link:         response.Link,
+		UPC:          response.UPC,
+		Artist:       response.Artist,
+		Contributors: response.Contributors,
+		Genres:       response.Genres.Data,
+		Label:        response.Label,
+		NumTracks:    response.NumTracks,
+		Duration:     response.Duration,
+		RecordType:   response.RecordType,
+		Explicit:     response.Explicit,
+		CoverURL:     response.CoverURL,
 		Covers: Covers{
 			Small:  response.CoverSmall,
 			Medium: response.CoverMedium,
@@ -78,61 +132,67 @@ func NewAlbum(response *AlbumResponse, api *API) (*Album, error) {
 	return &album, nil
 }
 
-// albumRequest performs the API request for the deezer album
-// remember to close the body
-func (api *API) albumRequest(ID int) (*http.Response, error) {
-	// construct the request
-	req, err := http.NewRequest(http.MethodGet,
-		fmt.Sprintf(AlbumAPIFormat, ID),
-		nil)
-
-	if err != nil {
-		return nil, err
+// GenreNames returns the names of the album's genres
+func (album *Album) GenreNames() []string {
+	names := make([]string, len(album.Genres))
+	for i, genre := range album.Genres {
+		names[i] = genre.Name
 	}
+	return names
+}
 
-	// send the request
-	resp, err := api.client.Do(req)
-	if err != nil {
-		return nil, err
+// GetAlbumData gets the album based on its ID, following the
+// pagination of its tracklist
+func (api *API) GetAlbumData(ID int) (*Album, error) {
+	var response AlbumResponse
+	if !api.cacheGet(CacheAlbum, ID, &response) {
+		if err := api.fetchAlbumData(ID, &response); err != nil {
+			return nil, err
+		}
+		api.cacheSet(CacheAlbum, ID, &response)
 	}
 
-	return resp, nil
+	// convert to album
+	return NewAlbum(&response, api)
 }
 
-// GetAlbum gets the album based on its ID
-func (api *API) GetAlbumData(ID int) (*Album, error) {
+// fetchAlbumData gets the album response from the public API,
+// including the whole tracklist
+func (api *API) fetchAlbumData(ID int, response *AlbumResponse) error {
 	// make a request to the public API
-	resp, err := api.albumRequest(ID)
+	resp, err := api.publicRequest(fmt.Sprintf(AlbumAPIFormat, ID))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
+	if api.Debug() {
+		DumpResponse(resp, "GetAlbumData")
+	}
 
 	// decode the json
-	var response AlbumResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, err
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return err
 	}
-
-	// convert to album
-	album, err := NewAlbum(&response, api)
-	if err != nil {
-		return nil, err
+	if response.Error != nil {
+		return response.Error
 	}
 
-	return album, nil
+	// get the rest of the tracklist
+	return api.getRemainingTracks(&response.Tracks)
 }
 
 // GetTracks gets all tracks in an album and store them in
 // album.Tracks. Also return the slice.
 func (album *Album) GetTracks() ([]*Track, error) {
-	for _, t := range album.Tracklist {
-		track, err := album.api.GetSongData(t.ID)
-		if err != nil {
-			return []*Track{}, err
-		}
-		album.Tracks = append(album.Tracks, track)
+	IDs := make([]int, len(album.Tracklist))
+	for i, t := range album.Tracklist {
+		IDs[i] = t.ID
+	}
+	tracks, err := album.api.GetSongListData(IDs)
+	if err != nil {
+		return []*Track{}, err
 	}
+	album.Tracks = append(album.Tracks, tracks...)
 
 	return album.Tracks, nil
 }
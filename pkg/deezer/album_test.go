@@ -0,0 +1,104 @@
+package deezer
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtureTransport answers requests with recorded responses from
+// testdata, keyed by the URL of the request
+type fixtureTransport map[string]string
+
+func (fixtures fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name, ok := fixtures[req.URL.String()]
+	if !ok {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       f,
+		Request:    req,
+	}, nil
+}
+
+// newFixtureAPI creates an API that only talks to the fixtures
+func newFixtureAPI(fixtures fixtureTransport) *API {
+	return &API{client: &http.Client{Transport: fixtures}}
+}
+
+func TestGetAlbumData(t *testing.T) {
+	api := newFixtureAPI(fixtureTransport{
+		"https://api.deezer.com/album/302127":                 "album_302127.json",
+		"https://api.deezer.com/album/302127/tracks?index=10": "album_302127_tracks_10.json",
+		"https://api.deezer.com/album/1":                      "album_not_found.json",
+	})
+
+	album, err := api.GetAlbumData(302127)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "Discovery", album.Title)
+	assert.Equal(t, "724384960650", album.UPC)
+	assert.Equal(t, 27, album.Artist.ID)
+	assert.Equal(t, "Daft Punk", album.Artist.Name)
+	assert.Equal(t, 1, len(album.Contributors))
+	assert.Equal(t, "Main", album.Contributors[0].Role)
+	assert.Equal(t, []string{"Dance", "Electro"}, album.GenreNames())
+	assert.Equal(t, "Parlophone (France)", album.Label)
+	assert.Equal(t, 14, album.NumTracks)
+	assert.Equal(t, 3660, album.Duration)
+	assert.Equal(t, RecordTypeAlbum, album.RecordType)
+	assert.False(t, album.Explicit)
+	assert.Equal(t, time.Date(2001, 3, 7, 0, 0, 0, 0, time.UTC), album.Date)
+	assert.Contains(t, album.Covers.XL, "1000x1000")
+
+	// the tracklist is spread over two pages
+	assert.Equal(t, 14, len(album.Tracklist))
+	assert.Equal(t, "One More Time", album.Tracklist[0].Title)
+	assert.Equal(t, "Too Long", album.Tracklist[13].Title)
+	assert.Equal(t, 600, album.Tracklist[13].Duration)
+	assert.Equal(t, "Daft Punk", album.Tracklist[13].Artist.Name)
+
+	_, err = api.GetAlbumData(1)
+	assert.Equal(t, "DataException: no data (code 800)", err.Error())
+}
+
+func TestNewAlbumUnknownDate(t *testing.T) {
+	album, err := NewAlbum(&AlbumResponse{ID: 1, Date: "0000-00-00"}, nil)
+	assert.Equal(t, nil, err)
+	assert.True(t, album.Date.IsZero())
+
+	_, err = NewAlbum(&AlbumResponse{ID: 1, Date: "March 2001"}, nil)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestGetAlbumDataCached(t *testing.T) {
+	api := newFixtureAPI(fixtureTransport{
+		"https://api.deezer.com/album/302127":                 "album_302127.json",
+		"https://api.deezer.com/album/302127/tracks?index=10": "album_302127_tracks_10.json",
+	})
+	api.SetCache(memoryCache{})
+	album, err := api.GetAlbumData(302127)
+	assert.Equal(t, nil, err)
+
+	// the whole tracklist comes from the cache once the fixtures are gone
+	api.client.Transport = fixtureTransport{}
+	cached, err := api.GetAlbumData(302127)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, album.Title, cached.Title)
+	assert.Equal(t, album.Date, cached.Date)
+	assert.Equal(t, 14, len(cached.Tracklist))
+	assert.Equal(t, album.Tracklist, cached.Tracklist)
+}
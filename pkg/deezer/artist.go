@@ -0,0 +1,182 @@
+package deezer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+const (
+	ArtistAPIFormat       = "https://api.deezer.com/artist/%d"
+	ArtistAlbumsAPIFormat = "https://api.deezer.com/artist/%d/albums?limit=%d"
+)
+
+// artistAlbumsPageSize is the number of releases requested per page
+const artistAlbumsPageSize = 100
+
+// Record types of releases, as given by the public API
+const (
+	RecordTypeAlbum       = "album"
+	RecordTypeEP          = "ep"
+	RecordTypeSingle      = "single"
+	RecordTypeCompilation = "compile"
+)
+
+// ArtistResponse is an intermediate format for getting artist data
+// that stores the data before putting it in an Artist struct
+type ArtistResponse struct {
+	ID            int             `json:"id"`
+	Name          string          `json:"name"`
+	Link          string          `json:"link"`
+	PictureSmall  string          `json:"picture_small"`
+	PictureMedium string          `json:"picture_medium"`
+	PictureBig    string          `json:"picture_big"`
+	PictureXL     string          `json:"picture_xl"`
+	NumAlbums     int             `json:"nb_album"`
+	NumFans       int             `json:"nb_fan"`
+	Error         *PublicAPIError `json:"error"`
+}
+
+// Artist stores the data for the artist of interest
+type Artist struct {
+	ID        int
+	Name      string
+	Link      string
+	Pictures  Covers
+	NumAlbums int
+	NumFans   int
+	Releases  []Release
+	api       *API
+}
+
+// Release is one of an artist's releases, as listed in their
+// discography
+type Release struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Link        string `json:"link"`
+	Cover       string `json:"cover_xl"`
+	ReleaseDate string `json:"release_date"`
+	RecordType  string `json:"record_type"`
+	Explicit    bool   `json:"explicit_lyrics"`
+	NumFans     int    `json:"fans"`
+}
+
+// Year returns the year of the release, or 0 if it is unknown
+func (release *Release) Year() int {
+	if len(release.ReleaseDate) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(release.ReleaseDate[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+// releasePage is one page of an artist's discography
+type releasePage struct {
+	Data  []Release       `json:"data"`
+	Next  string          `json:"next"`
+	Error *PublicAPIError `json:"error"`
+}
+
+// NewArtist creates an Artist from an ArtistResponse
+func NewArtist(response *ArtistResponse, api *API) *Artist {
+	return &Artist{
+		ID:   response.ID,
+		Name: response.Name,
+		Link: response.Link,
+		Pictures: Covers{
+			Small:  response.PictureSmall,
+			Medium: response.PictureMedium,
+			Big:    response.PictureBig,
+			XL:     response.PictureXL,
+		},
+		NumAlbums: response.NumAlbums,
+		NumFans:   response.NumFans,
+		api:       api,
+	}
+}
+
+// GetArtistData gets an artist based on their ID
+func (api *API) GetArtistData(ID int) (*Artist, error) {
+	var response ArtistResponse
+	if api.cacheGet(CacheArtist, ID, &response) {
+		return NewArtist(&response, api), nil
+	}
+
+	resp, err := api.publicRequest(fmt.Sprintf(ArtistAPIFormat, ID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if api.Debug() {
+		DumpResponse(resp, "GetArtistData")
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+	api.cacheSet(CacheArtist, ID, &response)
+	return NewArtist(&response, api), nil
+}
+
+// getReleasePage fetches one page of an artist's discography
+func (api *API) getReleasePage(url string) (*releasePage, error) {
+	resp, err := api.publicRequest(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var page releasePage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	if page.Error != nil {
+		return nil, page.Error
+	}
+	return &page, nil
+}
+
+// GetReleases gets the artist's whole discography, following the
+// pagination, and stores it in artist.Releases. If any record types
+// are given, only releases of those types are returned.
+func (artist *Artist) GetReleases(recordTypes ...string) ([]Release, error) {
+	if artist.Releases == nil {
+		var releases []Release
+		next := fmt.Sprintf(ArtistAlbumsAPIFormat, artist.ID, artistAlbumsPageSize)
+		for next != "" {
+			page, err := artist.api.getReleasePage(next)
+			if err != nil {
+				return nil, err
+			}
+			releases = append(releases, page.Data...)
+			next = page.Next
+		}
+		artist.Releases = releases
+	}
+	return FilterReleases(artist.Releases, recordTypes...), nil
+}
+
+// FilterReleases returns the releases of the given record types, or
+// all of them if no types are given
+func FilterReleases(releases []Release, recordTypes ...string) []Release {
+	if len(recordTypes) == 0 {
+		return releases
+	}
+	var filtered []Release
+	for _, release := range releases {
+		for _, recordType := range recordTypes {
+			if release.RecordType == recordType {
+				filtered = append(filtered, release)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,34 @@
+package deezer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleases(t *testing.T) {
+	const testData = `{
+		"data": [
+			{"id": 6575789, "title": "Random Access Memories", "release_date": "2013-05-20", "record_type": "album"},
+			{"id": 6710379, "title": "Get Lucky", "release_date": "2013-04-19", "record_type": "single"},
+			{"id": 302127, "title": "Discovery", "release_date": "2001-03-07", "record_type": "album"},
+			{"id": 1, "title": "Unknown", "release_date": "", "record_type": "compile"}
+		],
+		"total": 4,
+		"next": "https://api.deezer.com/artist/27/albums?index=4"
+	}`
+	var page releasePage
+	assert.Equal(t, nil, json.Unmarshal([]byte(testData), &page))
+	assert.Equal(t, 4, len(page.Data))
+	assert.Equal(t, "https://api.deezer.com/artist/27/albums?index=4", page.Next)
+
+	assert.Equal(t, 2013, page.Data[0].Year())
+	assert.Equal(t, 0, page.Data[3].Year())
+
+	albums := FilterReleases(page.Data, RecordTypeAlbum)
+	assert.Equal(t, 2, len(albums))
+	assert.Equal(t, 302127, albums[1].ID)
+	assert.Equal(t, 3, len(FilterReleases(page.Data, RecordTypeSingle, RecordTypeAlbum)))
+	assert.Equal(t, 4, len(FilterReleases(page.Data)))
+}
@@ -1,6 +1,7 @@
 package deezer
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"os"
@@ -15,19 +16,54 @@ const blowfishIV = "\x00\x01\x02\x03\x04\x05\x06\x07"
 
 const fileChunkSize = 2048
 
-// decryptBlowfish decrypts blowfish data
-func decryptBlowfish(key, data []byte) ([]byte, error) {
+// fileBufferSize is the size of the buffers used when decrypting files
+const fileBufferSize = 64 * 1024
+
+// chunkCipher encrypts and decrypts the encrypted chunks of a song.
+// Each chunk is encrypted separately with Blowfish in CBC mode,
+// starting from the same IV. The block cipher is created once per song
+// and chunks are processed in place, so a chunk does not allocate.
+type chunkCipher struct {
+	block cipher.Block
+}
+
+// newChunkCipher creates a chunkCipher for a song's key
+func newChunkCipher(key []byte) (*chunkCipher, error) {
 	block, err := blowfish.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
+	return &chunkCipher{block: block}, nil
+}
 
-	mode := cipher.NewCBCDecrypter(block, []byte(blowfishIV))
-
-	decrypted := make([]byte, len(data))
-	mode.CryptBlocks(decrypted, data)
+// decryptChunk decrypts a whole chunk in place
+func (c *chunkCipher) decryptChunk(chunk []byte) {
+	var prev, next [blowfish.BlockSize]byte
+	copy(prev[:], blowfishIV)
+	for i := 0; i+blowfish.BlockSize <= len(chunk); i += blowfish.BlockSize {
+		block := chunk[i : i+blowfish.BlockSize]
+		// keep the ciphertext for the next block before overwriting it
+		copy(next[:], block)
+		c.block.Decrypt(block, block)
+		for j := range block {
+			block[j] ^= prev[j]
+		}
+		prev = next
+	}
+}
 
-	return decrypted, nil
+// encryptChunk encrypts a whole chunk in place
+func (c *chunkCipher) encryptChunk(chunk []byte) {
+	var prev [blowfish.BlockSize]byte
+	copy(prev[:], blowfishIV)
+	for i := 0; i+blowfish.BlockSize <= len(chunk); i += blowfish.BlockSize {
+		block := chunk[i : i+blowfish.BlockSize]
+		for j := range block {
+			block[j] ^= prev[j]
+		}
+		c.block.Encrypt(block, block)
+		copy(prev[:], block)
+	}
 }
 
 // getBlowfishKey calculates the key required to decrypt the
@@ -43,9 +79,70 @@ func (track *Track) GetBlowfishKey() []byte {
 	return output
 }
 
+// DecryptSongStream decrypts a song downloaded from deezer as it is
+// read from r, writing it to w. Every third chunk, starting with the
+// first, is encrypted, except for a partial chunk at the end. Reads
+// always fill a whole chunk, so r may return short reads.
+func DecryptSongStream(key []byte, r io.Reader, w io.Writer) error {
+	c, err := newChunkCipher(key)
+	if err != nil {
+		return err
+	}
+	return cryptSongStream(r, w, c.decryptChunk)
+}
+
+// EncryptSongStream encrypts a song the same way deezer does, so that
+// DecryptSongStream with the same key gives back the original. This
+// is useful for building test data.
+func EncryptSongStream(key []byte, r io.Reader, w io.Writer) error {
+	c, err := newChunkCipher(key)
+	if err != nil {
+		return err
+	}
+	return cryptSongStream(r, w, c.encryptChunk)
+}
+
+// cryptSongStream copies r to w a chunk at a time, passing every third
+// whole chunk, starting with the first, through crypt
+func cryptSongStream(r io.Reader, w io.Writer, crypt func(chunk []byte)) error {
+	buf := make([]byte, fileChunkSize)
+	for chunk := 0; ; chunk++ {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			return nil
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		// only whole chunks are encrypted
+		if chunk%3 == 0 && n == fileChunkSize {
+			crypt(buf)
+		}
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+
+		if n < fileChunkSize {
+			return nil
+		}
+	}
+}
+
 // DecryptSongFile decrypts the encrypted chunks of a song downloaded
 // from deezer
 func DecryptSongFile(key []byte, inputPath, outputPath string) error {
+	return cryptSongFile(key, inputPath, outputPath, DecryptSongStream)
+}
+
+// EncryptSongFile encrypts a song file the same way deezer does. See
+// EncryptSongStream.
+func EncryptSongFile(key []byte, inputPath, outputPath string) error {
+	return cryptSongFile(key, inputPath, outputPath, EncryptSongStream)
+}
+
+// cryptSongFile runs a stream function from one file to another
+// through buffered I/O
+func cryptSongFile(key []byte, inputPath, outputPath string, crypt func(key []byte, r io.Reader, w io.Writer) error) error {
 	// open files
 	inFile, err := os.Open(inputPath)
 	if err != nil {
@@ -58,42 +155,12 @@ func DecryptSongFile(key []byte, inputPath, outputPath string) error {
 	}
 	defer outFile.Close()
 
-	buf := make([]byte, fileChunkSize)
-	n, err := inFile.Read(buf)
-	if err != nil && err != io.EOF {
+	writer := bufio.NewWriterSize(outFile, fileBufferSize)
+	if err := crypt(key, bufio.NewReaderSize(inFile, fileBufferSize), writer); err != nil {
 		return err
 	}
-
-	for chunk := 0; n > 0; chunk++ {
-		// only decrypt every third chunk (including first
-		// chunk)
-		encrypted := (chunk%3 == 0)
-
-		// only decrypt if encrypted and whole chunk
-		if encrypted && n == fileChunkSize {
-			buf, err = decryptBlowfish(key, buf)
-			if err != nil {
-				return err
-			}
-		}
-
-		// write the chunk back
-		n_read := n
-		n, err = outFile.Write(buf[:n_read])
-		if err != nil {
-			return err
-		}
-		// assert that bytes read = bytes written just in case
-		if n_read != n {
-			return fmt.Errorf("bytes read does not match bytes written: (%d != %d)", n_read, n)
-		}
-
-		// read next chunk
-		n, err = inFile.Read(buf)
-		if err != nil && err != io.EOF {
-			return err
-		}
+	if err := writer.Flush(); err != nil {
+		return err
 	}
-
-	return nil
+	return outFile.Close()
 }
@@ -1,9 +1,19 @@
 package deezer
 
 import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blowfish"
 )
 
 func TestGetBlowfishKey(t *testing.T) {
@@ -13,3 +23,197 @@ func TestGetBlowfishKey(t *testing.T) {
 	result := testTrack.GetBlowfishKey()
 	assert.Equal(t, testResult, string(result))
 }
+
+// encryptTestSong encrypts plaintext the way deezer does, using the
+// standard library's CBC mode rather than the package's own chunk
+// cipher, so that the package is checked against something
+// independent of it
+func encryptTestSong(t testing.TB, key, plaintext []byte) []byte {
+	block, err := blowfish.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+	encrypted := append([]byte{}, plaintext...)
+	for i := 0; i+fileChunkSize <= len(encrypted); i += fileChunkSize * 3 {
+		chunk := encrypted[i : i+fileChunkSize]
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(chunk, chunk)
+	}
+	return encrypted
+}
+
+func TestSongKnownAnswer(t *testing.T) {
+	// a chunk of zeros encrypted with the key of testTrack, from
+	// blowfish in CBC mode with the IV 00 01 ... 07
+	const (
+		first = "1a77b9c8eb60f18b56c950e85e3e55bc"
+		last  = "2fa1b8ed632def47"
+	)
+	key := testTrack.GetBlowfishKey()
+	plaintext := make([]byte, fileChunkSize)
+	assert.Equal(t, first, hex.EncodeToString(encryptTestSong(t, key, plaintext)[:16]))
+
+	var encrypted bytes.Buffer
+	assert.Equal(t, nil, EncryptSongStream(key, bytes.NewReader(plaintext), &encrypted))
+	assert.Equal(t, first, hex.EncodeToString(encrypted.Bytes()[:16]))
+	assert.Equal(t, last, hex.EncodeToString(encrypted.Bytes()[fileChunkSize-8:]))
+
+	var decrypted bytes.Buffer
+	assert.Equal(t, nil, DecryptSongStream(key, bytes.NewReader(encrypted.Bytes()), &decrypted))
+	assert.True(t, bytes.Equal(plaintext, decrypted.Bytes()))
+}
+
+// testSong returns random song data of the given length
+func testSong(length int) []byte {
+	data := make([]byte, length)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func TestDecryptSongStream(t *testing.T) {
+	key := testTrack.GetBlowfishKey()
+	for _, test := range []struct {
+		name   string
+		length int
+	}{
+		{"Whole Chunks", fileChunkSize * 6},
+		{"Trailing Partial Chunk", fileChunkSize*3 + 100},
+		{"Partial First Chunk", 100},
+		{"Empty", 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			plaintext := testSong(test.length)
+			encrypted := encryptTestSong(t, key, plaintext)
+
+			readers := map[string]io.Reader{
+				"Full":     bytes.NewReader(encrypted),
+				"OneByte":  iotest.OneByteReader(bytes.NewReader(encrypted)),
+				"HalfRead": iotest.HalfReader(bytes.NewReader(encrypted)),
+			}
+			for name, r := range readers {
+				var out bytes.Buffer
+				assert.Equal(t, nil, DecryptSongStream(key, r, &out), name)
+				assert.True(t, bytes.Equal(plaintext, out.Bytes()), name)
+			}
+		})
+	}
+
+	t.Run("Read Error", func(t *testing.T) {
+		r := iotest.TimeoutReader(bytes.NewReader(testSong(fileChunkSize * 3)))
+		err := DecryptSongStream(key, r, ioutil.Discard)
+		assert.Equal(t, iotest.ErrTimeout, err)
+	})
+}
+
+func TestDecryptSongFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deezer")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	key := testTrack.GetBlowfishKey()
+	plaintext := testSong(fileBufferSize*2 + 1000)
+	inPath := filepath.Join(dir, "song.enc")
+	outPath := filepath.Join(dir, "song.mp3")
+	assert.Equal(t, nil, ioutil.WriteFile(inPath, encryptTestSong(t, key, plaintext), 0644))
+
+	assert.Equal(t, nil, DecryptSongFile(key, inPath, outPath))
+	decrypted, err := ioutil.ReadFile(outPath)
+	assert.Equal(t, nil, err)
+	assert.True(t, bytes.Equal(plaintext, decrypted))
+}
+
+func TestDecryptChunkAllocs(t *testing.T) {
+	c, err := newChunkCipher(testTrack.GetBlowfishKey())
+	assert.Equal(t, nil, err)
+	chunk := testSong(fileChunkSize)
+	allocs := testing.AllocsPerRun(100, func() {
+		c.decryptChunk(chunk)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func BenchmarkDecryptChunk(b *testing.B) {
+	c, err := newChunkCipher(testTrack.GetBlowfishKey())
+	if err != nil {
+		b.Fatal(err)
+	}
+	chunk := testSong(fileChunkSize)
+	b.SetBytes(fileChunkSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.decryptChunk(chunk)
+	}
+}
+
+func BenchmarkDecryptSongStream(b *testing.B) {
+	key := testTrack.GetBlowfishKey()
+	encrypted := encryptTestSong(b, key, testSong(10*1024*1024))
+	b.SetBytes(int64(len(encrypted)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := DecryptSongStream(key, bytes.NewReader(encrypted), ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestEncryptSongStream(t *testing.T) {
+	key := testTrack.GetBlowfishKey()
+	plaintext := testSong(fileChunkSize*7 + 5)
+
+	var encrypted bytes.Buffer
+	assert.Equal(t, nil, EncryptSongStream(key, iotest.HalfReader(bytes.NewReader(plaintext)), &encrypted))
+	assert.True(t, bytes.Equal(encryptTestSong(t, key, plaintext), encrypted.Bytes()), "should match deezer's scheme")
+	// chunks that aren't encrypted are copied as they are
+	assert.True(t, bytes.Equal(plaintext[fileChunkSize:fileChunkSize*3], encrypted.Bytes()[fileChunkSize:fileChunkSize*3]))
+	assert.False(t, bytes.Equal(plaintext[:fileChunkSize], encrypted.Bytes()[:fileChunkSize]))
+}
+
+func TestEncryptSongFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deezer")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	key := testTrack.GetBlowfishKey()
+	plaintext := testSong(fileBufferSize + fileChunkSize + 17)
+	plainPath := filepath.Join(dir, "song.mp3")
+	encPath := filepath.Join(dir, "song.enc")
+	outPath := filepath.Join(dir, "song.out.mp3")
+	assert.Equal(t, nil, ioutil.WriteFile(plainPath, plaintext, 0644))
+
+	assert.Equal(t, nil, EncryptSongFile(key, plainPath, encPath))
+	assert.Equal(t, nil, DecryptSongFile(key, encPath, outPath))
+	decrypted, err := ioutil.ReadFile(outPath)
+	assert.Equal(t, nil, err)
+	assert.True(t, bytes.Equal(plaintext, decrypted))
+}
+
+func FuzzSongRoundTrip(f *testing.F) {
+	f.Add([]byte("g4el58wc0zvf9na1"), []byte{})
+	f.Add(testTrack.GetBlowfishKey(), testSong(fileChunkSize*3+1))
+	f.Add([]byte("key"), testSong(fileChunkSize))
+	f.Fuzz(func(t *testing.T, key, data []byte) {
+		var encrypted bytes.Buffer
+		err := EncryptSongStream(key, bytes.NewReader(data), &encrypted)
+		if err != nil {
+			// blowfish only accepts keys of 1 to 56 bytes
+			if len(key) >= 1 && len(key) <= 56 {
+				t.Fatalf("unexpected error for a %d byte key: %s", len(key), err)
+			}
+			return
+		}
+		if encrypted.Len() != len(data) {
+			t.Fatalf("encrypted length %d != %d", encrypted.Len(), len(data))
+		}
+
+		var decrypted bytes.Buffer
+		if err := DecryptSongStream(key, iotest.OneByteReader(&encrypted), &decrypted); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, decrypted.Bytes()) {
+			t.Fatal("round trip changed the data")
+		}
+	})
+}
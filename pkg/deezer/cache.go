@@ -0,0 +1,94 @@
+package deezer
+
+import (
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Methods that responses are cached under
+const (
+	CacheSong     = getSongMethod
+	CacheAlbum    = "album"
+	CacheArtist   = "artist"
+	CachePlaylist = "playlist"
+)
+
+// Cache stores metadata responses so that they do not have to be
+// fetched again. Entries are keyed by the method they came from and
+// the ID they are for, and the cache decides how long to keep each
+// method's entries. A Cache must be safe for concurrent use.
+type Cache interface {
+	// Get returns the cached data, or false if there is none or it
+	// has expired
+	Get(method string, ID int) ([]byte, bool)
+	// Set stores data in the cache
+	Set(method string, ID int, data []byte) error
+	// Delete removes the cached data, if there is any
+	Delete(method string, ID int) error
+}
+
+// SetCache sets the cache used for metadata. A nil cache turns
+// caching off.
+func (api *API) SetCache(cache Cache) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.cache = cache
+}
+
+// cacheGet decodes the cached response for method and ID into v,
+// returning whether there was one
+func (api *API) cacheGet(method string, ID int, v interface{}) bool {
+	api.mu.RLock()
+	cache := api.cache
+	api.mu.RUnlock()
+	if cache == nil {
+		return false
+	}
+
+	data, ok := cache.Get(method, ID)
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false
+	}
+	return true
+}
+
+// cacheSet stores the response for method and ID. Failing to do so
+// is only logged, since the response can always be fetched again.
+func (api *API) cacheSet(method string, ID int, v interface{}) {
+	api.mu.RLock()
+	cache := api.cache
+	api.mu.RUnlock()
+	if cache == nil {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = cache.Set(method, ID, data)
+	}
+	if err != nil {
+		logrus.Warnf("failed to cache %s %d: %s", method, ID, err)
+	}
+}
+
+// Uncache removes the track's cached song data, so that it is fetched
+// again next time. This should be done when its download URL is
+// rejected, since the data may be out of date.
+func (track *Track) Uncache() {
+	if track.api == nil {
+		return
+	}
+	track.api.mu.RLock()
+	cache := track.api.cache
+	track.api.mu.RUnlock()
+	if cache == nil {
+		return
+	}
+	if err := cache.Delete(CacheSong, track.ID); err != nil {
+		logrus.Warnf("failed to uncache %s %d: %s", CacheSong, track.ID, err)
+	}
+}
@@ -0,0 +1,43 @@
+package deezer
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ContributorRole is the role of a contributor to a track, as named in
+// the gateway's SNG_CONTRIBUTORS data
+type ContributorRole string
+
+const (
+	RoleFeaturing ContributorRole = "featuring"
+	RoleComposer  ContributorRole = "composer"
+	RoleAuthor    ContributorRole = "author"
+	RoleProducer  ContributorRole = "producer"
+	RoleMixer     ContributorRole = "mixer"
+	RoleEngineer  ContributorRole = "engineer"
+)
+
+// TrackContributors maps each role to the names credited with it, in
+// the order Deezer lists them. Roles that are not known are kept.
+type TrackContributors map[ContributorRole][]string
+
+// UnmarshalJSON decodes the contributors. Tracks without contributors
+// have an empty array rather than an empty object.
+func (contributors *TrackContributors) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		*contributors = nil
+		return nil
+	}
+	var roles map[ContributorRole][]string
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return err
+	}
+	*contributors = roles
+	return nil
+}
+
+// Get returns the names credited with a role
+func (contributors TrackContributors) Get(role ContributorRole) []string {
+	return contributors[role]
+}
@@ -1,18 +1,21 @@
 package deezer
 
 import (
-	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"sync"
+	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/joshbarrass/deezerdl/pkg/ratelimit"
 )
 
 const (
 	getTokenMethod      = "deezer.getUserData"
 	getSongMethod       = "song.getData"
+	getSongListMethod   = "song.getListData"
 	getSongMobileMethod = "song_getData"
 )
 
@@ -34,10 +37,32 @@ var deezerUrl = url.URL{
 	Path:   "/",
 }
 
+// PublicAPIError is the error object returned by the public API
+type PublicAPIError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+func (err *PublicAPIError) Error() string {
+	return fmt.Sprintf("%s: %s (code %d)", err.Type, err.Message, err.Code)
+}
+
+// API is a client for the deezer gateway and public APIs. An API is
+// safe for concurrent use by multiple goroutines: its token, user and
+// debug setting are guarded by a mutex, and the http client and cookie
+// jar are safe for concurrent use themselves.
 type API struct {
-	APIToken  string
-	client    *http.Client
-	DebugMode bool
+	client *http.Client
+
+	mu             sync.RWMutex
+	token          string
+	tokenTime      time.Time
+	debug          bool
+	user           *User
+	sessionHandler func(*Session)
+	cache          Cache
+	limiters       map[Endpoint]*ratelimit.Limiter
 }
 
 // NewAPI creates a new API with a http Client with cookie jar
@@ -50,12 +75,41 @@ func NewAPI(debugMode bool) (*API, error) {
 		Jar: cookieJar,
 	}
 	api := API{
-		client:    &client,
-		DebugMode: debugMode,
+		client: &client,
+		debug:  debugMode,
 	}
 	return &api, nil
 }
 
+// Token returns the API token used for gateway requests, which is set
+// by CookieLogin and GetUserData
+func (api *API) Token() string {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+	return api.token
+}
+
+// SetToken replaces the API token used for gateway requests
+func (api *API) SetToken(token string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.token = token
+}
+
+// Debug returns whether responses are dumped to the log
+func (api *API) Debug() bool {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+	return api.debug
+}
+
+// SetDebug sets whether responses are dumped to the log
+func (api *API) SetDebug(debug bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.debug = debug
+}
+
 // ApiRequest performs an API request
 func (api *API) ApiRequest(method string, body io.Reader) (*http.Response, error) {
 	// add the required parameters to the URL
@@ -70,7 +124,7 @@ func (api *API) ApiRequest(method string, body io.Reader) (*http.Response, error
 	if method == getTokenMethod {
 		q.Set("api_token", "null")
 	} else {
-		q.Set("api_token", api.APIToken)
+		q.Set("api_token", api.Token())
 	}
 	u.RawQuery = q.Encode()
 
@@ -86,6 +140,7 @@ func (api *API) ApiRequest(method string, body io.Reader) (*http.Response, error
 	req.Header.Add("User-Agent", "PostmanRuntime/7.21.0")
 
 	// send
+	api.wait(EndpointGateway)
 	resp, err := api.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -106,14 +161,7 @@ func (api *API) MobileApiRequest(method string, body io.Reader) (*http.Response,
 	}
 
 	// get the current sid from the cookie jar
-	var sid string
-	for _, cookie := range api.client.Jar.Cookies(&deezerUrl) {
-		if cookie.Name == "sid" {
-			sid = cookie.Value
-			break
-		}
-	}
-	q.Set("sid", sid)
+	q.Set("sid", api.cookie("sid"))
 
 	u.RawQuery = q.Encode()
 
@@ -129,6 +177,7 @@ func (api *API) MobileApiRequest(method string, body io.Reader) (*http.Response,
 	req.Header.Add("User-Agent", "PostmanRuntime/7.21.0")
 
 	// send
+	api.wait(EndpointGateway)
 	resp, err := api.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -141,13 +190,7 @@ func (api *API) MobileApiRequest(method string, body io.Reader) (*http.Response,
 // from a browser
 func (api *API) CookieLogin(arl string) error {
 	// add the cookie to the jar
-	cookie := http.Cookie{
-		Name:   "arl",
-		Value:  arl,
-		Domain: ".deezer.com",
-		Path:   "/",
-	}
-	api.client.Jar.SetCookies(&deezerUrl, []*http.Cookie{&cookie})
+	api.setCookie("arl", arl)
 
 	// get a session
 	err := api.getSession()
@@ -155,48 +198,17 @@ func (api *API) CookieLogin(arl string) error {
 		return err
 	}
 
-	// try to get the token
-	api.APIToken, err = api.getToken()
+	// try to get the token, which also checks whether the cookie was
+	// accepted
+	user, err := api.GetUserData()
 	if err != nil {
 		return err
 	}
-
-	return nil
-}
-
-// GetToken gets the user's API token
-func (api *API) getToken() (string, error) {
-	// make the request
-	resp, err := api.ApiRequest(getTokenMethod, nil)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	if api.DebugMode {
-		DumpResponse(resp, "GetToken")
-	}
-
-	// decode result key into a struct from the body
-	var data struct {
-		Results json.RawMessage `json:"results"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil { // uses the body directly
-		return "", err
-	}
-	// and then the checkForm key (the token)
-	var results struct {
-		Token string `json:"checkForm"`
-	}
-	if err := json.Unmarshal(data.Results, &results); err != nil {
-		return "", err
+	if user.ID == 0 {
+		return ErrNotLoggedIn
 	}
 
-	if api.DebugMode {
-		logrus.WithFields(logrus.Fields{
-			"token": results.Token,
-		}).Info("got api token")
-	}
-	return results.Token, nil
+	return nil
 }
 
 // GetSession makes a request to the base URL to get any required
@@ -211,11 +223,13 @@ func (api *API) getSession() error {
 	}
 
 	// send
+	api.wait(EndpointGateway)
 	resp, err := api.client.Do(req)
 	if err != nil {
 		return err
 	}
-	if api.DebugMode {
+	defer resp.Body.Close()
+	if api.Debug() {
 		DumpResponse(resp, "GetSession")
 	}
 	return nil
@@ -1,6 +1,12 @@
 package deezer
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,3 +21,280 @@ func TestGetDownloadURL(t *testing.T) {
 	assert.Equal(t, nil, err, "An error should not occur")
 	assert.Equal(t, testResult, result, "The result should match the expected result")
 }
+
+func TestDecodeTrack(t *testing.T) {
+	const testData = `{
+		"SNG_ID": "3135553",
+		"SNG_TITLE": "One More Time",
+		"MD5_ORIGIN": "43808a3ac856cc117362ab94718603ba",
+		"MEDIA_VERSION": "7",
+		"DURATION": "320",
+		"ART_ID": "27",
+		"ART_NAME": "Daft Punk",
+		"ARTISTS": [{"ART_ID": "27", "ART_NAME": "Daft Punk"}],
+		"SNG_CONTRIBUTORS": {
+			"main_artist": ["Daft Punk"],
+			"composer": ["Thomas Bangalter", "Guy-Manuel de Homem-Christo", "Anthony Moore"],
+			"producer": ["Daft Punk"],
+			"label": ["Parlophone"]
+		},
+		"ALB_PICTURE": "2e018122cb56986277102d2041a592c8",
+		"FILESIZE_FLAC": "33184547",
+		"FILESIZE_MP3_320": 12825806,
+		"FILESIZE_MP3_256": ""
+	}`
+	track, err := decodeTrack([]byte(testData), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, testTrack.ID, track.ID)
+	assert.Equal(t, int64(33184547), track.FileSize(FLAC))
+	assert.Equal(t, int64(12825806), track.FileSize(MP3_320))
+	assert.Equal(t, int64(0), track.FileSize(MP3_256))
+	assert.Equal(t, int64(0), track.FileSize(MP3_128))
+	assert.Equal(t, 320, track.Duration)
+	assert.Equal(t, []string{"Daft Punk"}, track.ArtistNames())
+	assert.Equal(t, []string{"Thomas Bangalter", "Guy-Manuel de Homem-Christo", "Anthony Moore"}, track.Contributors.Get(RoleComposer))
+	assert.Equal(t, []string{"Parlophone"}, track.Contributors.Get("label"))
+	assert.Equal(t, 0, len(track.Contributors.Get(RoleAuthor)))
+	assert.Equal(t, "https://e-cdns-images.dzcdn.net/images/cover/2e018122cb56986277102d2041a592c8/500x500-000000-80-0-0.jpg", track.CoverURL(500))
+
+	// tracks without contributors have an empty array
+	track, err = decodeTrack([]byte(`{"SNG_ID": "1", "SNG_CONTRIBUTORS": []}`), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(track.Contributors))
+}
+
+// gatewayTransport answers gateway requests with the given results,
+// keyed by method, recording the api_token each request was made with.
+// If validToken is set, requests made with any other token are
+// rejected.
+type gatewayTransport struct {
+	results    map[string]string
+	validToken string
+
+	mu     sync.Mutex
+	tokens []string
+}
+
+func (gateway *gatewayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	q := req.URL.Query()
+	gateway.mu.Lock()
+	gateway.tokens = append(gateway.tokens, q.Get("api_token"))
+	gateway.mu.Unlock()
+
+	body := `{"error":{"VALID_TOKEN_REQUIRED":"Invalid CSRF token"},"results":{}}`
+	method := q.Get("method")
+	if gateway.validToken == "" || method == getTokenMethod || q.Get("api_token") == gateway.validToken {
+		results, ok := gateway.results[method]
+		if !ok {
+			results = "{}"
+		}
+		body = `{"error":[],"results":` + results + `}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func newGatewayAPI(t *testing.T, results map[string]string) (*API, *gatewayTransport) {
+	api, err := NewAPI(false)
+	assert.Equal(t, nil, err)
+	gateway := &gatewayTransport{results: results}
+	api.client.Transport = gateway
+	return api, gateway
+}
+
+func TestTokenAccessors(t *testing.T) {
+	api, gateway := newGatewayAPI(t, map[string]string{
+		getTokenMethod: `{"checkForm": "token", "USER": {"USER_ID": 1234}}`,
+		getSongMethod:  `{"SNG_ID": "3135553"}`,
+	})
+	assert.Equal(t, "", api.Token())
+	assert.Equal(t, (*User)(nil), api.CurrentUser())
+
+	user, err := api.GetUserData()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "token", api.Token())
+	assert.Equal(t, user, api.CurrentUser())
+
+	api.SetToken("other")
+	_, err = api.GetSongData(3135553)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"null", "other"}, gateway.tokens)
+
+	assert.False(t, api.Debug())
+	api.SetDebug(true)
+	assert.True(t, api.Debug())
+}
+
+// TestAPIConcurrentUse shares one API between goroutines that log in,
+// make requests and change its settings at the same time. Run with
+// -race to check for data races.
+func TestAPIConcurrentUse(t *testing.T) {
+	const workers = 8
+	api, _ := newGatewayAPI(t, map[string]string{
+		getTokenMethod: `{"checkForm": "token", "USER": {"USER_ID": 1234}}`,
+		getSongMethod:  `{"SNG_ID": "3135553"}`,
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			errs <- api.CookieLogin("arl")
+		}()
+		go func() {
+			defer wg.Done()
+			track, err := api.GetSongData(3135553)
+			if err == nil && track.ID != 3135553 {
+				err = fmt.Errorf("got track %d", track.ID)
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			api.SetToken("token")
+			api.Token()
+			api.CurrentUser()
+			errs <- nil
+		}()
+		go func() {
+			defer wg.Done()
+			api.SetDebug(false)
+			api.Debug()
+			errs <- nil
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Equal(t, nil, err)
+	}
+	assert.Equal(t, "token", api.Token())
+	assert.Equal(t, 1234, api.CurrentUser().ID)
+}
+
+// songListTransport answers song.getListData and song.getData requests
+// with minimal song data, leaving the missing tracks out of the lists
+type songListTransport struct {
+	missing map[int]bool
+	chunks  []int
+	singles []int
+	mu      sync.Mutex
+}
+
+func (songs *songListTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var params struct {
+		ID  int   `json:"SNG_ID"`
+		IDs []int `json:"SNG_IDS"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		return nil, err
+	}
+
+	songData := func(ID int) string {
+		return fmt.Sprintf(`{"SNG_ID": "%d", "MD5_ORIGIN": "md5-%d", "MEDIA_VERSION": "2"}`, ID, ID)
+	}
+	songs.mu.Lock()
+	defer songs.mu.Unlock()
+	var results string
+	switch req.URL.Query().Get("method") {
+	case getSongListMethod:
+		songs.chunks = append(songs.chunks, len(params.IDs))
+		var data []string
+		for _, ID := range params.IDs {
+			if !songs.missing[ID] {
+				data = append(data, songData(ID))
+			}
+		}
+		results = fmt.Sprintf(`{"data": [%s], "count": %d}`, strings.Join(data, ","), len(data))
+	case getSongMethod:
+		songs.singles = append(songs.singles, params.ID)
+		results = songData(params.ID)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"error":[],"results":` + results + `}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestGetSongListData(t *testing.T) {
+	songs := &songListTransport{missing: map[int]bool{150: true}}
+	api := &API{client: &http.Client{Transport: songs}}
+
+	IDs := make([]int, 250)
+	for i := range IDs {
+		IDs[i] = 250 - i
+	}
+	tracks, err := api.GetSongListData(IDs)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int{100, 100, 50}, songs.chunks)
+	assert.Equal(t, []int{150}, songs.singles, "missing tracks should be fetched on their own")
+
+	assert.Equal(t, len(IDs), len(tracks))
+	for i, track := range tracks {
+		assert.Equal(t, IDs[i], track.ID)
+		assert.Equal(t, fmt.Sprintf("md5-%d", IDs[i]), track.MD5)
+		assert.Equal(t, 2, track.MediaVersion)
+	}
+
+	tracks, err = api.GetSongListData(nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(tracks))
+}
+
+// memoryCache is a Cache that keeps everything in memory forever
+type memoryCache map[string][]byte
+
+func (cache memoryCache) Get(method string, ID int) ([]byte, bool) {
+	data, ok := cache[fmt.Sprintf("%s:%d", method, ID)]
+	return data, ok
+}
+
+func (cache memoryCache) Set(method string, ID int, data []byte) error {
+	cache[fmt.Sprintf("%s:%d", method, ID)] = data
+	return nil
+}
+
+func (cache memoryCache) Delete(method string, ID int) error {
+	delete(cache, fmt.Sprintf("%s:%d", method, ID))
+	return nil
+}
+
+func TestCache(t *testing.T) {
+	songs := &songListTransport{missing: map[int]bool{}}
+	api := &API{client: &http.Client{Transport: songs}}
+	cache := memoryCache{}
+	api.SetCache(cache)
+
+	// songs fetched on their own or in a list are both cached
+	_, err := api.GetSongData(1)
+	assert.Equal(t, nil, err)
+	_, err = api.GetSongListData([]int{2, 3})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(cache))
+
+	tracks, err := api.GetSongListData([]int{1, 2, 3, 4})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, len(tracks))
+	assert.Equal(t, "md5-1", tracks[0].MD5)
+	assert.Equal(t, []int{1}, songs.singles)
+	assert.Equal(t, []int{2, 1}, songs.chunks, "only the uncached track should be requested")
+
+	track, err := api.GetSongData(3)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, track.ID)
+	assert.Equal(t, []int{1}, songs.singles)
+
+	// uncached tracks are fetched again
+	track.Uncache()
+	_, err = api.GetSongData(3)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int{1, 3}, songs.singles)
+}
@@ -0,0 +1,104 @@
+package deezer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joshbarrass/deezerdl/pkg/lrc"
+)
+
+const getLyricsMethod = "song.getLyrics"
+
+// noDataError is the gateway error type sent when there is nothing to
+// return, such as for a track without lyrics
+const noDataError = "DATA_ERROR"
+
+// ErrNoLyrics is returned by GetLyrics for tracks without lyrics
+var ErrNoLyrics = errors.New("track has no lyrics")
+
+// LyricLine is one line of synchronised lyrics
+type LyricLine struct {
+	// Time is when the line starts, from the start of the track
+	Time time.Duration
+	Text string
+}
+
+// Lyrics stores the lyrics of a track. Lines is empty if the lyrics
+// are not synchronised.
+type Lyrics struct {
+	Text    string
+	Lines   []LyricLine
+	Writers string
+}
+
+// lyricsResults stores the song.getLyrics results
+type lyricsResults struct {
+	Text    string `json:"LYRICS_TEXT"`
+	Writers string `json:"LYRICS_WRITERS"`
+	Sync    []struct {
+		Milliseconds flexInt `json:"milliseconds"`
+		Line         string  `json:"line"`
+	} `json:"LYRICS_SYNC_JSON"`
+}
+
+// Synced returns whether the lyrics have timed lines
+func (lyrics *Lyrics) Synced() bool {
+	return len(lyrics.Lines) > 0
+}
+
+// LRC formats the timed lines as an LRC file, or returns an empty
+// string if the lyrics are not synchronised
+func (lyrics *Lyrics) LRC() string {
+	var b strings.Builder
+	for _, line := range lyrics.Lines {
+		b.WriteString(lrc.FormatTime(line.Time))
+		b.WriteString(line.Text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// decodeLyrics converts the song.getLyrics results to Lyrics
+func decodeLyrics(data []byte) (*Lyrics, error) {
+	var results lyricsResults
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	lyrics := Lyrics{
+		Text:    strings.ReplaceAll(results.Text, "\r\n", "\n"),
+		Writers: results.Writers,
+	}
+	for _, line := range results.Sync {
+		// the sync data has blank entries between verses
+		if line.Line == "" {
+			continue
+		}
+		lyrics.Lines = append(lyrics.Lines, LyricLine{
+			Time: time.Duration(line.Milliseconds) * time.Millisecond,
+			Text: line.Line,
+		})
+	}
+	if lyrics.Text == "" && len(lyrics.Lines) == 0 {
+		return nil, ErrNoLyrics
+	}
+	return &lyrics, nil
+}
+
+// GetLyrics gets the lyrics of the track, returning ErrNoLyrics if it
+// has none
+func (track *Track) GetLyrics() (*Lyrics, error) {
+	params := fmt.Sprintf(`{"sng_id":"%d"}`, track.ID)
+	results, err := track.api.gatewayRequest(getLyricsMethod, params, "GetLyrics")
+	if gatewayErr, ok := err.(GatewayError); ok {
+		if _, noData := gatewayErr[noDataError]; noData {
+			return nil, ErrNoLyrics
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeLyrics(results)
+}
@@ -0,0 +1,59 @@
+package deezer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLyrics(t *testing.T) {
+	api, _ := newGatewayAPI(t, map[string]string{
+		getLyricsMethod: `{
+			"LYRICS_ID": "2780622",
+			"LYRICS_TEXT": "One more time\r\nWe're gonna celebrate",
+			"LYRICS_WRITERS": "Anthony Moore, Thomas Bangalter",
+			"LYRICS_SYNC_JSON": [
+				{"lrc_timestamp": "[00:03.58]", "milliseconds": "3580", "duration": "2000", "line": "One more time"},
+				{"line": ""},
+				{"lrc_timestamp": "[01:02.30]", "milliseconds": "62300", "duration": "2000", "line": "We're gonna celebrate"}
+			]
+		}`,
+	})
+	track := &Track{ID: 3135553, api: api}
+
+	lyrics, err := track.GetLyrics()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "One more time\nWe're gonna celebrate", lyrics.Text)
+	assert.Equal(t, "Anthony Moore, Thomas Bangalter", lyrics.Writers)
+	assert.True(t, lyrics.Synced())
+	assert.Equal(t, []LyricLine{
+		{Time: 3580 * time.Millisecond, Text: "One more time"},
+		{Time: 62300 * time.Millisecond, Text: "We're gonna celebrate"},
+	}, lyrics.Lines)
+	assert.Equal(t, "[00:03.58]One more time\n[01:02.30]We're gonna celebrate\n", lyrics.LRC())
+
+	_, err = decodeLyrics([]byte(`{"LYRICS_ID": "1", "LYRICS_SYNC_JSON": []}`))
+	assert.Equal(t, ErrNoLyrics, err)
+}
+
+// gatewayErrorTransport answers every request with a gateway error
+type gatewayErrorTransport string
+
+func (gatewayErr gatewayErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"error":` + string(gatewayErr) + `,"results":{}}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestGetLyricsNoData(t *testing.T) {
+	api := &API{client: &http.Client{Transport: gatewayErrorTransport(`{"DATA_ERROR": "lyrics not found"}`)}}
+	track := &Track{ID: 1, api: api}
+	_, err := track.GetLyrics()
+	assert.Equal(t, ErrNoLyrics, err)
+}
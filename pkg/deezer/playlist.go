@@ -0,0 +1,150 @@
+package deezer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const PlaylistAPIFormat = "https://api.deezer.com/playlist/%d"
+
+// PlaylistResponse is an intermediate format for getting playlist data
+// that stores the data before putting it in a Playlist struct
+type PlaylistResponse struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Link        string `json:"link"`
+	NumTracks   int    `json:"nb_tracks"`
+	Creator     struct {
+		Name string `json:"name"`
+	} `json:"creator"`
+	Tracks trackPage       `json:"tracks"`
+	Error  *PublicAPIError `json:"error"`
+}
+
+// trackPage is one page of a track listing from the public API
+type trackPage struct {
+	Data []AlbumTrack `json:"data"`
+	Next string       `json:"next"`
+}
+
+// Playlist stores the data for the playlist of interest
+type Playlist struct {
+	ID          int
+	Title       string
+	Description string
+	Link        string
+	Creator     string
+	Tracklist   []AlbumTrack
+	Tracks      []*Track
+	api         *API
+}
+
+// NewPlaylist creates a Playlist from a PlaylistResponse
+func NewPlaylist(response *PlaylistResponse, api *API) *Playlist {
+	return &Playlist{
+		ID:          response.ID,
+		Title:       response.Title,
+		Description: response.Description,
+		Link:        response.Link,
+		Creator:     response.Creator.Name,
+		Tracklist:   response.Tracks.Data,
+		api:         api,
+	}
+}
+
+// publicRequest performs a GET request against the public API
+// remember to close the body
+func (api *API) publicRequest(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	api.wait(EndpointPublic)
+	return api.client.Do(req)
+}
+
+// getTrackPage fetches the next page of a track listing
+func (api *API) getTrackPage(url string) (*trackPage, error) {
+	resp, err := api.publicRequest(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var page struct {
+		trackPage
+		Error *PublicAPIError `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	if page.Error != nil {
+		return nil, page.Error
+	}
+	return &page.trackPage, nil
+}
+
+// getRemainingTracks follows the pagination of a track listing,
+// appending every later page to the first
+func (api *API) getRemainingTracks(tracks *trackPage) error {
+	for tracks.Next != "" {
+		page, err := api.getTrackPage(tracks.Next)
+		if err != nil {
+			return err
+		}
+		tracks.Data = append(tracks.Data, page.Data...)
+		tracks.Next = page.Next
+	}
+	return nil
+}
+
+// GetPlaylistData gets a public playlist based on its ID, following
+// the pagination of its tracklist
+func (api *API) GetPlaylistData(ID int) (*Playlist, error) {
+	var response PlaylistResponse
+	if api.cacheGet(CachePlaylist, ID, &response) {
+		return NewPlaylist(&response, api), nil
+	}
+
+	resp, err := api.publicRequest(fmt.Sprintf(PlaylistAPIFormat, ID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if api.Debug() {
+		DumpResponse(resp, "GetPlaylistData")
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	// get the rest of the tracklist
+	if err := api.getRemainingTracks(&response.Tracks); err != nil {
+		return nil, err
+	}
+	api.cacheSet(CachePlaylist, ID, &response)
+
+	return NewPlaylist(&response, api), nil
+}
+
+// GetTracks gets all tracks in a playlist and stores them in
+// playlist.Tracks. Also returns the slice.
+func (playlist *Playlist) GetTracks() ([]*Track, error) {
+	IDs := make([]int, len(playlist.Tracklist))
+	for i, t := range playlist.Tracklist {
+		IDs[i] = t.ID
+	}
+	tracks, err := playlist.api.GetSongListData(IDs)
+	if err != nil {
+		return []*Track{}, err
+	}
+	playlist.Tracks = append(playlist.Tracks, tracks...)
+
+	return playlist.Tracks, nil
+}
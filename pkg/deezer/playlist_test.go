@@ -0,0 +1,27 @@
+package deezer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPlaylistData(t *testing.T) {
+	api := newFixtureAPI(fixtureTransport{
+		"https://api.deezer.com/playlist/908622995":                "playlist_908622995.json",
+		"https://api.deezer.com/playlist/908622995/tracks?index=2": "playlist_908622995_tracks_2.json",
+	})
+
+	playlist, err := api.GetPlaylistData(908622995)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "Daft Punk Essentials", playlist.Title)
+	assert.Equal(t, "Deezer Editor", playlist.Creator)
+
+	// the tracklist is spread over two pages
+	assert.Equal(t, 3, len(playlist.Tracklist))
+	assert.Equal(t, "One More Time", playlist.Tracklist[0].Title)
+	assert.Equal(t, "Harder, Better, Faster, Stronger", playlist.Tracklist[2].Title)
+
+	_, err = api.GetPlaylistData(1)
+	assert.NotEqual(t, nil, err)
+}
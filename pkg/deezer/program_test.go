@@ -33,7 +33,8 @@ func TestProgram(t *testing.T) {
 
 		err := api.CookieLogin(config.ArlCookie)
 		assert.Equal(t, nil, err)
-		assert.NotEqual(t, "", api.APIToken)
+		assert.NotEqual(t, "", api.Token())
+		assert.NotEqual(t, 0, api.CurrentUser().ID)
 	})
 
 	t.Run("Get Download Link", func(t *testing.T) {
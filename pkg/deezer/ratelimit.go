@@ -0,0 +1,53 @@
+package deezer
+
+import (
+	"net/http"
+
+	"github.com/joshbarrass/deezerdl/pkg/ratelimit"
+)
+
+// Endpoint is one of the services the API talks to, each of which
+// can be rate limited separately
+type Endpoint string
+
+const (
+	// EndpointGateway is the private gateway, including the mobile
+	// gateway
+	EndpointGateway Endpoint = "gateway"
+	// EndpointPublic is the public API at api.deezer.com
+	EndpointPublic Endpoint = "public"
+	// EndpointCDN is the CDN that tracks are downloaded from
+	EndpointCDN Endpoint = "cdn"
+)
+
+// SetRateLimit limits the requests made to an endpoint. The limiter is
+// shared by every goroutine using the API. A nil limiter removes the
+// limit.
+func (api *API) SetRateLimit(endpoint Endpoint, limiter *ratelimit.Limiter) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if api.limiters == nil {
+		api.limiters = make(map[Endpoint]*ratelimit.Limiter)
+	}
+	api.limiters[endpoint] = limiter
+}
+
+// wait blocks until a request to the endpoint is allowed
+func (api *API) wait(endpoint Endpoint) {
+	api.mu.RLock()
+	limiter := api.limiters[endpoint]
+	api.mu.RUnlock()
+	limiter.Wait()
+}
+
+// CDNRequest performs a GET request against the CDN, such as for a
+// URL from GetDownloadURL
+// remember to close the body
+func (api *API) CDNRequest(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	api.wait(EndpointCDN)
+	return api.client.Do(req)
+}
@@ -0,0 +1,77 @@
+package deezer
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joshbarrass/deezerdl/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// sleepRecorder is a ratelimit.Clock that stands still and records how
+// long it was asked to sleep
+type sleepRecorder struct {
+	slept time.Duration
+	mu    sync.Mutex
+}
+
+func (clock *sleepRecorder) Now() time.Time {
+	return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (clock *sleepRecorder) Sleep(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.slept += d
+}
+
+// routeTransport sends gateway requests and public API requests to
+// different transports
+type routeTransport struct {
+	gateway, public http.RoundTripper
+}
+
+func (route routeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == apiUrl.Host {
+		return route.gateway.RoundTrip(req)
+	}
+	return route.public.RoundTrip(req)
+}
+
+func TestRateLimit(t *testing.T) {
+	const workers = 5
+	api := &API{client: &http.Client{Transport: routeTransport{
+		gateway: &songListTransport{missing: map[int]bool{}},
+		public: fixtureTransport{
+			"https://api.deezer.com/album/302127":                 "album_302127.json",
+			"https://api.deezer.com/album/302127/tracks?index=10": "album_302127_tracks_10.json",
+		},
+	}}}
+	gatewayClock, publicClock := &sleepRecorder{}, &sleepRecorder{}
+	api.SetRateLimit(EndpointGateway, ratelimit.NewLimiterWithClock(2, 1, gatewayClock))
+	api.SetRateLimit(EndpointPublic, ratelimit.NewLimiterWithClock(10, 1, publicClock))
+
+	// the gateway limit is shared by every goroutine
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(ID int) {
+			defer wg.Done()
+			_, err := api.GetSongData(ID)
+			assert.Equal(t, nil, err)
+		}(i + 1)
+	}
+	wg.Wait()
+	// the first request is free, then they wait 0.5s, 1s, 1.5s and 2s
+	assert.Equal(t, 5*time.Second, gatewayClock.slept)
+	assert.Equal(t, time.Duration(0), publicClock.slept)
+
+	// the album and the second page of its tracklist only count
+	// against the public limit
+	_, err := api.GetAlbumData(302127)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 5*time.Second, gatewayClock.slept)
+	assert.Equal(t, 100*time.Millisecond, publicClock.slept)
+}
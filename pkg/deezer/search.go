@@ -0,0 +1,135 @@
+package deezer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+const SearchAPIFormat = "https://api.deezer.com/search/%s?q=%s&index=%d&limit=%d"
+
+// Types of search
+const (
+	SearchTracks    = "track"
+	SearchAlbums    = "album"
+	SearchArtists   = "artist"
+	SearchPlaylists = "playlist"
+)
+
+// ArtistResult is an artist in search results
+type ArtistResult struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Link      string `json:"link"`
+	Picture   string `json:"picture_xl"`
+	NumAlbums int    `json:"nb_album"`
+	NumFans   int    `json:"nb_fan"`
+}
+
+// AlbumResult is an album in search results
+type AlbumResult struct {
+	ID         int          `json:"id"`
+	Title      string       `json:"title"`
+	Link       string       `json:"link"`
+	Cover      string       `json:"cover_xl"`
+	NumTracks  int          `json:"nb_tracks"`
+	RecordType string       `json:"record_type"`
+	Explicit   bool         `json:"explicit_lyrics"`
+	Artist     ArtistResult `json:"artist"`
+}
+
+// TrackResult is a track in search results
+type TrackResult struct {
+	ID       int          `json:"id"`
+	Title    string       `json:"title"`
+	Link     string       `json:"link"`
+	Duration int          `json:"duration"`
+	Explicit bool         `json:"explicit_lyrics"`
+	Artist   ArtistResult `json:"artist"`
+	Album    AlbumResult  `json:"album"`
+}
+
+// PlaylistResult is a playlist in search results
+type PlaylistResult struct {
+	ID        int    `json:"id"`
+	Title     string `json:"title"`
+	Link      string `json:"link"`
+	NumTracks int    `json:"nb_tracks"`
+	User      struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+// searchResponse is one page of search results, before the results
+// are decoded into their type
+type searchResponse struct {
+	Data  json.RawMessage `json:"data"`
+	Total int             `json:"total"`
+	Error *PublicAPIError `json:"error"`
+}
+
+// search runs a search of the given type, decoding the page of results
+// starting at index into results and returning the total number of
+// results
+func (api *API) search(searchType, query string, index, limit int, results interface{}) (int, error) {
+	resp, err := api.publicRequest(fmt.Sprintf(SearchAPIFormat, searchType, url.QueryEscape(query), index, limit))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if api.Debug() {
+		DumpResponse(resp, "search")
+	}
+	return decodeSearch(resp.Body, results)
+}
+
+// decodeSearch decodes a page of search results
+func decodeSearch(r io.Reader, results interface{}) (int, error) {
+	var response searchResponse
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return 0, err
+	}
+	if response.Error != nil {
+		return 0, response.Error
+	}
+	if len(response.Data) == 0 {
+		return response.Total, nil
+	}
+	if err := json.Unmarshal(response.Data, results); err != nil {
+		return 0, err
+	}
+	return response.Total, nil
+}
+
+// SearchTracks searches for tracks, returning up to limit results
+// starting at index, and the total number of results
+func (api *API) SearchTracks(query string, index, limit int) ([]TrackResult, int, error) {
+	var results []TrackResult
+	total, err := api.search(SearchTracks, query, index, limit, &results)
+	return results, total, err
+}
+
+// SearchAlbums searches for albums, returning up to limit results
+// starting at index, and the total number of results
+func (api *API) SearchAlbums(query string, index, limit int) ([]AlbumResult, int, error) {
+	var results []AlbumResult
+	total, err := api.search(SearchAlbums, query, index, limit, &results)
+	return results, total, err
+}
+
+// SearchArtists searches for artists, returning up to limit results
+// starting at index, and the total number of results
+func (api *API) SearchArtists(query string, index, limit int) ([]ArtistResult, int, error) {
+	var results []ArtistResult
+	total, err := api.search(SearchArtists, query, index, limit, &results)
+	return results, total, err
+}
+
+// SearchPlaylists searches for playlists, returning up to limit
+// results starting at index, and the total number of results
+func (api *API) SearchPlaylists(query string, index, limit int) ([]PlaylistResult, int, error) {
+	var results []PlaylistResult
+	total, err := api.search(SearchPlaylists, query, index, limit, &results)
+	return results, total, err
+}
@@ -0,0 +1,41 @@
+package deezer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeSearch(t *testing.T) {
+	const testData = `{
+		"data": [{
+			"id": 3135553,
+			"title": "One More Time",
+			"duration": 320,
+			"explicit_lyrics": false,
+			"artist": {"id": 27, "name": "Daft Punk"},
+			"album": {"id": 302127, "title": "Discovery"}
+		}],
+		"total": 283,
+		"next": "https://api.deezer.com/search/track?q=one+more+time&index=1&limit=1"
+	}`
+	var results []TrackResult
+	total, err := decodeSearch(strings.NewReader(testData), &results)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 283, total)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, 3135553, results[0].ID)
+	assert.Equal(t, "Daft Punk", results[0].Artist.Name)
+	assert.Equal(t, 302127, results[0].Album.ID)
+
+	// past the last page
+	results = nil
+	total, err = decodeSearch(strings.NewReader(`{"data": [], "total": 283}`), &results)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 283, total)
+	assert.Equal(t, 0, len(results))
+
+	_, err = decodeSearch(strings.NewReader(`{"error": {"type": "DataException", "message": "no data", "code": 800}}`), &results)
+	assert.Equal(t, "DataException: no data (code 800)", err.Error())
+}
@@ -0,0 +1,206 @@
+package deezer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SessionLifetime is how long a session is reused for before a new
+// token is fetched
+const SessionLifetime = time.Hour
+
+// invalidTokenError is the gateway error type sent when the api token
+// has expired or does not belong to the session
+const invalidTokenError = "VALID_TOKEN_REQUIRED"
+
+// ErrSessionExpired is returned when a saved session is too old or
+// was made with a different arl cookie
+var ErrSessionExpired = errors.New("session has expired")
+
+// Session stores the sid cookie and token of a logged in API, so that
+// they can be reused by a later run instead of logging in again. The
+// arl cookie itself is not kept, only its hash, so that a session can
+// be matched to the arl it was made with.
+type Session struct {
+	ARLHash string    `json:"arl_sha256"`
+	SID     string    `json:"sid"`
+	Token   string    `json:"token"`
+	User    *User     `json:"user"`
+	Expires time.Time `json:"expires"`
+}
+
+// HashARL returns the hex-encoded SHA-256 hash of an arl cookie
+func HashARL(arl string) string {
+	sum := sha256.Sum256([]byte(arl))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoggedIn returns whether the arl cookie was accepted when the
+// session was made
+func (session *Session) LoggedIn() bool {
+	return session.User != nil && session.User.ID != 0
+}
+
+// Valid returns whether the session can be reused at time now with
+// the given arl cookie
+func (session *Session) Valid(arl string, now time.Time) bool {
+	return session.LoggedIn() &&
+		session.ARLHash == HashARL(arl) &&
+		session.Token != "" &&
+		now.Before(session.Expires)
+}
+
+// GatewayError is the error object returned by the gateway, keyed by
+// the type of error
+type GatewayError map[string]interface{}
+
+func (err GatewayError) Error() string {
+	types := make([]string, 0, len(err))
+	for errType := range err {
+		types = append(types, errType)
+	}
+	sort.Strings(types)
+	messages := make([]string, len(types))
+	for i, errType := range types {
+		messages[i] = fmt.Sprintf("%s: %v", errType, err[errType])
+	}
+	return strings.Join(messages, "; ")
+}
+
+// InvalidToken returns whether the gateway rejected the api token
+func (err GatewayError) InvalidToken() bool {
+	_, ok := err[invalidTokenError]
+	return ok
+}
+
+// cookie returns the value of the named deezer cookie from the jar
+func (api *API) cookie(name string) string {
+	for _, cookie := range api.client.Jar.Cookies(&deezerUrl) {
+		if cookie.Name == name {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// setCookie puts a deezer cookie in the jar
+func (api *API) setCookie(name, value string) {
+	cookie := http.Cookie{
+		Name:   name,
+		Value:  value,
+		Domain: ".deezer.com",
+		Path:   "/",
+	}
+	api.client.Jar.SetCookies(&deezerUrl, []*http.Cookie{&cookie})
+}
+
+// Session returns the current session, which expires SessionLifetime
+// after the token was fetched
+func (api *API) Session() *Session {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+	return api.session()
+}
+
+// session returns the current session. api.mu must be held.
+func (api *API) session() *Session {
+	return &Session{
+		ARLHash: HashARL(api.cookie("arl")),
+		SID:     api.cookie("sid"),
+		Token:   api.token,
+		User:    api.user,
+		Expires: api.tokenTime.Add(SessionLifetime),
+	}
+}
+
+// ResumeSession logs in using a session saved by an earlier run
+// instead of fetching a new one. ErrSessionExpired is returned if the
+// session cannot be used with the arl cookie.
+func (api *API) ResumeSession(session *Session, arl string) error {
+	if session == nil || !session.Valid(arl, time.Now()) {
+		return ErrSessionExpired
+	}
+	api.setCookie("arl", arl)
+	if session.SID != "" {
+		api.setCookie("sid", session.SID)
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.token = session.Token
+	api.user = session.User
+	api.tokenTime = session.Expires.Add(-SessionLifetime)
+	return nil
+}
+
+// OnSessionChange sets a function that is called with the new session
+// whenever a token is fetched, including when an expired token is
+// replaced. If the arl cookie was rejected, the session is not
+// LoggedIn.
+func (api *API) OnSessionChange(handler func(*Session)) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.sessionHandler = handler
+}
+
+// gatewayRequest performs a gateway request with the given JSON
+// parameters and returns the results. If the gateway rejects the
+// token, a new one is fetched and the request is tried again.
+func (api *API) gatewayRequest(method, params, message string) (json.RawMessage, error) {
+	results, err := api.gatewayCall(method, params, message)
+	if gatewayErr, ok := err.(GatewayError); ok && gatewayErr.InvalidToken() && method != getTokenMethod {
+		user, err := api.GetUserData()
+		if err != nil {
+			return nil, err
+		}
+		if user.ID == 0 {
+			return nil, ErrNotLoggedIn
+		}
+		return api.gatewayCall(method, params, message)
+	}
+	return results, err
+}
+
+// gatewayCall performs a single gateway request, decoding the results
+// and any error
+func (api *API) gatewayCall(method, params, message string) (json.RawMessage, error) {
+	var body io.Reader
+	if params != "" {
+		body = strings.NewReader(params)
+	}
+	resp, err := api.ApiRequest(method, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if api.Debug() {
+		DumpResponse(resp, message)
+	}
+
+	var data struct {
+		Error   json.RawMessage `json:"error"`
+		Results json.RawMessage `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	// no errors are sent as an empty list
+	if len(data.Error) > 0 && data.Error[0] == '{' {
+		var gatewayErr GatewayError
+		if err := json.Unmarshal(data.Error, &gatewayErr); err != nil {
+			return nil, err
+		}
+		if len(gatewayErr) > 0 {
+			return nil, gatewayErr
+		}
+	}
+	return data.Results, nil
+}
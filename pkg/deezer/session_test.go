@@ -0,0 +1,94 @@
+package deezer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testGatewayResults = map[string]string{
+	getTokenMethod: `{"checkForm": "token", "USER": {"USER_ID": 1234}}`,
+	getSongMethod:  `{"SNG_ID": "3135553"}`,
+}
+
+func TestSessionValid(t *testing.T) {
+	now := time.Now()
+	session := &Session{
+		ARLHash: HashARL("arl"),
+		Token:   "token",
+		User:    &User{ID: 1234},
+		Expires: now.Add(time.Minute),
+	}
+	assert.True(t, session.Valid("arl", now))
+	assert.False(t, session.Valid("other", now), "the arl cookie has changed")
+	assert.False(t, session.Valid("arl", now.Add(time.Hour)), "the session has expired")
+
+	session.User.ID = 0
+	assert.False(t, session.Valid("arl", now), "the arl cookie was rejected")
+}
+
+func TestResumeSession(t *testing.T) {
+	api, _ := newGatewayAPI(t, testGatewayResults)
+	api.setCookie("arl", "arl")
+	_, err := api.GetUserData()
+	assert.Equal(t, nil, err)
+	api.setCookie("sid", "sid")
+
+	session := api.Session()
+	assert.Equal(t, HashARL("arl"), session.ARLHash)
+	assert.Equal(t, "sid", session.SID)
+	assert.Equal(t, "token", session.Token)
+	assert.Equal(t, 1234, session.User.ID)
+	assert.WithinDuration(t, time.Now().Add(SessionLifetime), session.Expires, time.Minute)
+
+	resumed, gateway := newGatewayAPI(t, testGatewayResults)
+	assert.Equal(t, ErrSessionExpired, resumed.ResumeSession(session, "other"))
+	assert.Equal(t, nil, resumed.ResumeSession(session, "arl"))
+	assert.Equal(t, session, resumed.Session())
+	assert.Equal(t, "arl", resumed.cookie("arl"))
+
+	// the resumed session is used without fetching a new token
+	_, err = resumed.GetSongData(3135553)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"token"}, gateway.tokens)
+}
+
+func TestRejectedToken(t *testing.T) {
+	api, gateway := newGatewayAPI(t, testGatewayResults)
+	gateway.validToken = "token"
+	api.setCookie("arl", "arl")
+	api.SetToken("expired")
+
+	var sessions []*Session
+	api.OnSessionChange(func(session *Session) {
+		sessions = append(sessions, session)
+	})
+
+	// the expired token is replaced and the request made again
+	track, err := api.GetSongData(3135553)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3135553, track.ID)
+	assert.Equal(t, []string{"expired", "null", "token"}, gateway.tokens)
+	assert.Equal(t, 1, len(sessions))
+	assert.Equal(t, "token", sessions[0].Token)
+	assert.True(t, sessions[0].LoggedIn())
+
+	// if the arl cookie is rejected too, the request fails
+	gateway.results = map[string]string{getTokenMethod: `{"checkForm": "other", "USER": {"USER_ID": 0}}`}
+	api.SetToken("expired")
+	_, err = api.GetSongData(3135553)
+	assert.Equal(t, ErrNotLoggedIn, err)
+	assert.Equal(t, 2, len(sessions))
+	assert.False(t, sessions[1].LoggedIn())
+}
+
+func TestGatewayError(t *testing.T) {
+	err := GatewayError{
+		"VALID_TOKEN_REQUIRED": "Invalid CSRF token",
+		"DATA_ERROR":           "song not found",
+	}
+	assert.Equal(t, "DATA_ERROR: song not found; VALID_TOKEN_REQUIRED: Invalid CSRF token", err.Error())
+	assert.True(t, err.InvalidToken())
+	assert.False(t, GatewayError{"DATA_ERROR": "song not found"}.InvalidToken())
+}
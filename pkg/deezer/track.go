@@ -20,16 +20,95 @@ const (
 const (
 	downloadHostFormat = "e-cdns-proxy-%c.dzcdn.net"
 	downloadPathFormat = "/mobile/1/%s"
+	coverURLFormat     = "https://e-cdns-images.dzcdn.net/images/cover/%s/%dx%d-000000-80-0-0.jpg"
 )
 
 type Track struct {
-	ID           int     `json:"SNG_ID,string"`
-	Title        string  `json:"SNG_TITLE"`
-	TrackNumber  int     `json:"TRACK_NUMBER,string"`
-	Gain         float32 `json:"GAIN,string"`
-	MD5          string  `json:"MD5_ORIGIN"`
-	MediaVersion int     `json:"MEDIA_VERSION,string"`
-	api          *API
+	ID           int               `json:"SNG_ID,string"`
+	Title        string            `json:"SNG_TITLE"`
+	TrackNumber  int               `json:"TRACK_NUMBER,string"`
+	DiskNumber   int               `json:"DISK_NUMBER,string"`
+	Gain         float32           `json:"GAIN,string"`
+	MD5          string            `json:"MD5_ORIGIN"`
+	MediaVersion int               `json:"MEDIA_VERSION,string"`
+	Duration     int               `json:"DURATION,string"`
+	ISRC         string            `json:"ISRC"`
+	ArtistID     int               `json:"ART_ID,string"`
+	Artist       string            `json:"ART_NAME"`
+	Artists      []TrackArtist     `json:"ARTISTS"`
+	Contributors TrackContributors `json:"SNG_CONTRIBUTORS"`
+	AlbumID      int               `json:"ALB_ID,string"`
+	AlbumTitle   string            `json:"ALB_TITLE"`
+	AlbumPicture string            `json:"ALB_PICTURE"`
+	// FileSizes stores the size in bytes of each available format
+	FileSizes map[Format]int64 `json:"-"`
+	api       *API
+}
+
+// TrackArtist is one of the artists credited on a track
+type TrackArtist struct {
+	ID   int    `json:"ART_ID,string"`
+	Name string `json:"ART_NAME"`
+}
+
+// ArtistNames returns the names of all artists credited on the track,
+// main artist first
+func (track *Track) ArtistNames() []string {
+	if len(track.Artists) == 0 {
+		if track.Artist == "" {
+			return nil
+		}
+		return []string{track.Artist}
+	}
+	names := make([]string, len(track.Artists))
+	for i, artist := range track.Artists {
+		names[i] = artist.Name
+	}
+	return names
+}
+
+// CoverURL returns the URL of the album cover at the given size in
+// pixels, or an empty string if the track has no cover
+func (track *Track) CoverURL(size int) string {
+	if track.AlbumPicture == "" {
+		return ""
+	}
+	return fmt.Sprintf(coverURLFormat, track.AlbumPicture, size, size)
+}
+
+// trackFileSizes stores the FILESIZE_* keys of the track data, which
+// may be numbers or strings
+type trackFileSizes struct {
+	FLAC   flexInt `json:"FILESIZE_FLAC"`
+	MP3320 flexInt `json:"FILESIZE_MP3_320"`
+	MP3256 flexInt `json:"FILESIZE_MP3_256"`
+	MP3128 flexInt `json:"FILESIZE_MP3_128"`
+}
+
+// FileSize returns the expected size in bytes of the track in the
+// given format, or 0 if it is unknown or not available
+func (track *Track) FileSize(format Format) int64 {
+	return track.FileSizes[format]
+}
+
+// decodeTrack decodes a track from the gateway's song data
+func decodeTrack(data []byte, api *API) (*Track, error) {
+	var track Track
+	if err := json.Unmarshal(data, &track); err != nil {
+		return nil, err
+	}
+	var sizes trackFileSizes
+	if err := json.Unmarshal(data, &sizes); err != nil {
+		return nil, err
+	}
+	track.FileSizes = map[Format]int64{
+		FLAC:    int64(sizes.FLAC),
+		MP3_320: int64(sizes.MP3320),
+		MP3_256: int64(sizes.MP3256),
+		MP3_128: int64(sizes.MP3128),
+	}
+	track.api = api
+	return &track, nil
 }
 
 var NoMD5Error = errors.New("no MD5 hash -- try authenticating")
@@ -62,7 +141,7 @@ func (track *Track) GetMD5() error {
 		return err
 	}
 	defer resp.Body.Close()
-	if track.api.DebugMode {
+	if track.api.Debug() {
 		DumpResponse(resp, "GetMD5")
 	}
 
@@ -92,31 +171,106 @@ func (track *Track) GetMD5() error {
 
 // GetSongData gets a track
 func (api *API) GetSongData(ID int) (*Track, error) {
+	var results json.RawMessage
+	if api.cacheGet(CacheSong, ID, &results) {
+		return decodeTrack(results, api)
+	}
+
 	// make the request
-	body := strings.NewReader(fmt.Sprintf(`{"SNG_ID":%d}`, ID))
-	resp, err := api.ApiRequest(getSongMethod, body)
+	results, err := api.gatewayRequest(getSongMethod, fmt.Sprintf(`{"SNG_ID":%d}`, ID), "GetSongData")
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if api.DebugMode {
-		DumpResponse(resp, "GetSongData")
-	}
+	api.cacheSet(CacheSong, ID, results)
 
-	// decode results key
-	var data struct {
-		Results json.RawMessage `json:"results"`
+	// decode track from results
+	return decodeTrack(results, api)
+}
+
+// songListChunkSize is the number of tracks requested in each
+// song.getListData request
+const songListChunkSize = 100
+
+// GetSongListData gets many tracks, in the same order as their IDs,
+// using as few requests as possible. The tracks are fully populated,
+// including their MD5 and media version, so GetMD5 is not needed to
+// download them.
+func (api *API) GetSongListData(IDs []int) ([]*Track, error) {
+	tracks := make([]*Track, 0, len(IDs))
+	for start := 0; start < len(IDs); start += songListChunkSize {
+		end := start + songListChunkSize
+		if end > len(IDs) {
+			end = len(IDs)
+		}
+		chunk, err := api.getSongListChunk(IDs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, chunk...)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
+	return tracks, nil
+}
+
+// getSongListChunk gets the tracks with the given IDs in a single
+// request, leaving out any that are cached
+func (api *API) getSongListChunk(IDs []int) ([]*Track, error) {
+	byID := make(map[int]*Track, len(IDs))
+	var uncached []int
+	for _, ID := range IDs {
+		var results json.RawMessage
+		if !api.cacheGet(CacheSong, ID, &results) {
+			uncached = append(uncached, ID)
+			continue
+		}
+		track, err := decodeTrack(results, api)
+		if err != nil {
+			return nil, err
+		}
+		byID[ID] = track
 	}
 
-	// decode track from results
-	var track Track
-	if err := json.Unmarshal(data.Results, &track); err != nil {
-		return nil, err
+	if len(uncached) > 0 {
+		params, err := json.Marshal(struct {
+			IDs []int `json:"SNG_IDS"`
+		}{uncached})
+		if err != nil {
+			return nil, err
+		}
+		results, err := api.gatewayRequest(getSongListMethod, string(params), "GetSongListData")
+		if err != nil {
+			return nil, err
+		}
+
+		var data struct {
+			Data []json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(results, &data); err != nil {
+			return nil, err
+		}
+		for _, trackData := range data.Data {
+			track, err := decodeTrack(trackData, api)
+			if err != nil {
+				return nil, err
+			}
+			byID[track.ID] = track
+			// the list has the same song data as song.getData
+			api.cacheSet(CacheSong, track.ID, trackData)
+		}
 	}
-	track.api = api
 
-	return &track, nil
+	// the list may leave some tracks out, so get any that are missing
+	// on their own
+	tracks := make([]*Track, len(IDs))
+	for i, ID := range IDs {
+		track, ok := byID[ID]
+		if !ok {
+			var err error
+			track, err = api.GetSongData(ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		tracks[i] = track
+	}
+	return tracks, nil
 }
@@ -0,0 +1,113 @@
+package deezer
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrNotLoggedIn = errors.New("not logged in -- the arl cookie may be invalid or expired")
+
+// User stores the account details of the logged in user
+type User struct {
+	ID              int
+	Name            string
+	Offer           string
+	Country         string
+	HQAllowed       bool
+	LosslessAllowed bool
+}
+
+// flexInt is an int that may be encoded in JSON as either a number or
+// a string. Empty strings and nulls decode as 0.
+type flexInt int
+
+func (i *flexInt) UnmarshalJSON(data []byte) error {
+	unquoted, err := strconv.Unquote(string(data))
+	if err != nil {
+		unquoted = string(data)
+	}
+	if unquoted == "" || unquoted == "null" {
+		*i = 0
+		return nil
+	}
+	n, err := strconv.Atoi(unquoted)
+	if err != nil {
+		return err
+	}
+	*i = flexInt(n)
+	return nil
+}
+
+// userDataResults stores the parts of the deezer.getUserData results
+// that are used
+type userDataResults struct {
+	Token     string `json:"checkForm"`
+	OfferName string `json:"OFFER_NAME"`
+	User      struct {
+		ID      flexInt `json:"USER_ID"`
+		Name    string  `json:"BLOG_NAME"`
+		Options struct {
+			WebHQ          bool   `json:"web_hq"`
+			MobileHQ       bool   `json:"mobile_hq"`
+			WebLossless    bool   `json:"web_lossless"`
+			MobileLossless bool   `json:"mobile_lossless"`
+			Country        string `json:"license_country"`
+		} `json:"OPTIONS"`
+	} `json:"USER"`
+}
+
+// GetUserData gets the details of the user the API is logged in as.
+// This also refreshes the API token. If the arl cookie was not
+// accepted, the returned user has an ID of 0.
+func (api *API) GetUserData() (*User, error) {
+	// make the request
+	data, err := api.gatewayCall(getTokenMethod, "", "GetUserData")
+	if err != nil {
+		return nil, err
+	}
+
+	// and then the user data and checkForm key (the token)
+	var results userDataResults
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+
+	if api.Debug() {
+		logrus.WithFields(logrus.Fields{
+			"token": results.Token,
+		}).Info("got api token")
+	}
+	options := results.User.Options
+	user := User{
+		ID:              int(results.User.ID),
+		Name:            results.User.Name,
+		Offer:           results.OfferName,
+		Country:         options.Country,
+		HQAllowed:       options.WebHQ || options.MobileHQ,
+		LosslessAllowed: options.WebLossless || options.MobileLossless,
+	}
+	api.mu.Lock()
+	api.token = results.Token
+	api.tokenTime = time.Now()
+	api.user = &user
+	session := api.session()
+	handler := api.sessionHandler
+	api.mu.Unlock()
+
+	if handler != nil {
+		handler(session)
+	}
+	return &user, nil
+}
+
+// CurrentUser returns the user found by the last call to CookieLogin
+// or GetUserData, or nil if neither has been called
+func (api *API) CurrentUser() *User {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+	return api.user
+}
@@ -0,0 +1,32 @@
+package deezer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserDataResults(t *testing.T) {
+	const testData = `{
+		"checkForm": "abcdef",
+		"OFFER_NAME": "Deezer Premium",
+		"USER": {
+			"USER_ID": "1234",
+			"BLOG_NAME": "someone",
+			"OPTIONS": {"web_lossless": true, "license_country": "GB"}
+		}
+	}`
+	var results userDataResults
+	err := json.Unmarshal([]byte(testData), &results)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "abcdef", results.Token)
+	assert.Equal(t, flexInt(1234), results.User.ID)
+	assert.Equal(t, "someone", results.User.Name)
+	assert.Equal(t, true, results.User.Options.WebLossless)
+
+	var id flexInt
+	err = json.Unmarshal([]byte(`0`), &id)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, flexInt(0), id)
+}
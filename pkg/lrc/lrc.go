@@ -0,0 +1,14 @@
+// Package lrc holds helpers for the LRC lyrics format shared by the
+// Deezer client and the tag writer
+package lrc
+
+import (
+	"fmt"
+	"time"
+)
+
+// FormatTime formats a time as an LRC timestamp, e.g. [01:02.34]
+func FormatTime(t time.Duration) string {
+	centiseconds := t.Milliseconds() / 10
+	return fmt.Sprintf("[%02d:%02d.%02d]", centiseconds/6000, centiseconds/100%60, centiseconds%100)
+}
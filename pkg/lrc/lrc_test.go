@@ -0,0 +1,15 @@
+package lrc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTime(t *testing.T) {
+	assert.Equal(t, "[00:00.00]", FormatTime(0))
+	assert.Equal(t, "[00:03.50]", FormatTime(3500*time.Millisecond))
+	assert.Equal(t, "[01:02.34]", FormatTime(62349*time.Millisecond))
+	assert.Equal(t, "[61:00.00]", FormatTime(61*time.Minute))
+}
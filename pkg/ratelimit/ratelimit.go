@@ -0,0 +1,114 @@
+// Package ratelimit provides a token bucket for limiting how often
+// something happens, shared between goroutines
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits. It is replaced in tests so that
+// limits can be checked without sleeping.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// realClock is the Clock of the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Limiter is a token bucket that fills at Rate tokens per second, up
+// to Burst tokens. Callers take tokens out with Wait or WaitN, which
+// block until the tokens would have been available, so that waiting
+// callers are served in the order they asked. A nil *Limiter never
+// blocks. All methods are safe for concurrent use.
+type Limiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  Clock
+	mu     sync.Mutex
+}
+
+// NewLimiter creates a full Limiter that allows rate events per
+// second with bursts of up to burst events. A rate of 0 or less
+// means no limit, and returns nil.
+func NewLimiter(rate float64, burst int) *Limiter {
+	return NewLimiterWithClock(rate, burst, realClock{})
+}
+
+// NewLimiterWithClock creates a Limiter like NewLimiter that uses the
+// given clock. Rates that are not finite numbers also mean no limit.
+func NewLimiterWithClock(rate float64, burst int, clock Clock) *Limiter {
+	if !(rate > 0) || math.IsInf(rate, 1) {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   clock.Now(),
+		clock:  clock,
+	}
+}
+
+// DefaultBurst returns the burst used for a rate when none is given,
+// which is one second's worth of events
+func DefaultBurst(rate float64) int {
+	if math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return 1
+	}
+	return int(math.Max(1, math.Ceil(rate)))
+}
+
+// Rate returns the number of events allowed per second
+func (limiter *Limiter) Rate() float64 {
+	if limiter == nil {
+		return math.Inf(1)
+	}
+	return limiter.rate
+}
+
+// Wait blocks until one event is allowed
+func (limiter *Limiter) Wait() {
+	limiter.WaitN(1)
+}
+
+// WaitN blocks until n events are allowed. n may be larger than the
+// burst, in which case the caller waits for the tokens to be refilled.
+func (limiter *Limiter) WaitN(n int) {
+	if limiter == nil || n <= 0 {
+		return
+	}
+	if wait := limiter.reserve(n); wait > 0 {
+		limiter.clock.Sleep(wait)
+	}
+}
+
+// reserve takes n tokens out of the bucket, which may leave it in
+// debt, and returns how long to wait until the debt is paid off
+func (limiter *Limiter) reserve(n int) time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	// refill the bucket for the time since the last reservation
+	now := limiter.clock.Now()
+	if elapsed := now.Sub(limiter.last); elapsed > 0 {
+		limiter.tokens = math.Min(limiter.burst, limiter.tokens+elapsed.Seconds()*limiter.rate)
+		limiter.last = now
+	}
+
+	limiter.tokens -= float64(n)
+	if limiter.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock only moves when told to. If advance is set, sleeping
+// moves it on by the time slept.
+type fakeClock struct {
+	now     time.Time
+	advance bool
+	slept   []time.Duration
+	mu      sync.Mutex
+}
+
+func newFakeClock(advance bool) *fakeClock {
+	return &fakeClock{
+		now:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		advance: advance,
+	}
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *fakeClock) Sleep(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.slept = append(clock.slept, d)
+	if clock.advance {
+		clock.now = clock.now.Add(d)
+	}
+}
+
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+}
+
+func TestLimiter(t *testing.T) {
+	clock := newFakeClock(true)
+	limiter := NewLimiterWithClock(10, 3, clock)
+
+	// the burst is allowed straight away
+	for i := 0; i < 3; i++ {
+		limiter.Wait()
+	}
+	assert.Equal(t, 0, len(clock.slept))
+
+	// then one event every 100ms
+	limiter.Wait()
+	limiter.Wait()
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond}, clock.slept)
+
+	// idle time refills the bucket, but only up to the burst
+	clock.Advance(time.Minute)
+	clock.slept = nil
+	limiter.WaitN(3)
+	assert.Equal(t, 0, len(clock.slept))
+
+	// asking for more than the burst waits for the refill
+	limiter.WaitN(5)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond}, clock.slept)
+}
+
+func TestLimiterShared(t *testing.T) {
+	const workers = 20
+	clock := newFakeClock(false)
+	limiter := NewLimiterWithClock(10, 1, clock)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.Wait()
+		}()
+	}
+	wg.Wait()
+
+	// the first event is free and each of the others waits its turn,
+	// whatever order the goroutines ran in
+	sort.Slice(clock.slept, func(i, j int) bool { return clock.slept[i] < clock.slept[j] })
+	assert.Equal(t, workers-1, len(clock.slept))
+	for i, d := range clock.slept {
+		assert.InDelta(t, float64(time.Duration(i+1)*100*time.Millisecond), float64(d), float64(time.Microsecond))
+	}
+}
+
+func TestNoLimit(t *testing.T) {
+	limiter := NewLimiter(0, 1)
+	assert.Equal(t, (*Limiter)(nil), limiter)
+	limiter.Wait()
+	limiter.WaitN(1000)
+	assert.Equal(t, 1, DefaultBurst(0.5))
+	assert.Equal(t, 3, DefaultBurst(2.5))
+
+	// rates that aren't numbers can't be limited
+	assert.Equal(t, (*Limiter)(nil), NewLimiter(math.NaN(), 1))
+	assert.Equal(t, (*Limiter)(nil), NewLimiter(math.Inf(1), 1))
+	assert.Equal(t, 1, DefaultBurst(math.NaN()))
+	assert.Equal(t, 1, DefaultBurst(math.Inf(1)))
+}
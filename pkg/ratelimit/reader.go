@@ -0,0 +1,32 @@
+package ratelimit
+
+import "io"
+
+// Reader limits the rate that bytes are read from an io.Reader, taking
+// one token from its Limiter per byte. Readers sharing a Limiter share
+// its rate.
+type Reader struct {
+	r       io.Reader
+	limiter *Limiter
+}
+
+// NewReader creates a Reader that reads from r at the rate of limiter.
+// A nil limiter reads as fast as r allows.
+func NewReader(r io.Reader, limiter *Limiter) *Reader {
+	return &Reader{
+		r:       r,
+		limiter: limiter,
+	}
+}
+
+// Read reads up to len(p) bytes, blocking afterwards until the limiter
+// allows them. Reads are no bigger than the limiter's burst, so that
+// data arrives smoothly rather than in bursts followed by long waits.
+func (reader *Reader) Read(p []byte) (int, error) {
+	if reader.limiter != nil && len(p) > int(reader.limiter.burst) {
+		p = p[:int(reader.limiter.burst)]
+	}
+	n, err := reader.r.Read(p)
+	reader.limiter.WaitN(n)
+	return n, err
+}
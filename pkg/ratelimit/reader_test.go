@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader(t *testing.T) {
+	clock := newFakeClock(true)
+	limiter := NewLimiterWithClock(1024, 1024, clock)
+	data := bytes.Repeat([]byte("x"), 10*1024)
+
+	start := clock.Now()
+	read, err := ioutil.ReadAll(NewReader(bytes.NewReader(data), limiter))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, data, read)
+
+	// the first KiB is the burst, and the other 9 take a second each
+	assert.Equal(t, 9*time.Second, clock.Now().Sub(start))
+	for _, d := range clock.slept {
+		assert.True(t, d <= time.Second, "reads should be no bigger than the burst")
+	}
+}
+
+func TestReaderShared(t *testing.T) {
+	const (
+		readers = 4
+		size    = 8 * 1024
+	)
+	// the clock stands still, so every read goes into debt and the
+	// last one waits for all the bytes read before it
+	clock := newFakeClock(false)
+	limiter := NewLimiterWithClock(1024, 1024, clock)
+
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := io.Copy(ioutil.Discard, NewReader(bytes.NewReader(make([]byte, size)), limiter))
+			assert.Equal(t, nil, err)
+			assert.Equal(t, int64(size), n)
+		}()
+	}
+	wg.Wait()
+
+	var longest time.Duration
+	for _, d := range clock.slept {
+		if d > longest {
+			longest = d
+		}
+	}
+	// 32KiB at 1KiB/s, less the 1KiB burst
+	assert.Equal(t, 31*time.Second, longest)
+}
+
+func TestReaderNoLimit(t *testing.T) {
+	data := []byte("hello")
+	read, err := ioutil.ReadAll(NewReader(bytes.NewReader(data), nil))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, data, read)
+}
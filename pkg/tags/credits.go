@@ -0,0 +1,82 @@
+package tags
+
+import (
+	"sort"
+	"strings"
+)
+
+// addValue appends a value to a field, skipping empty values
+func (tags Tags) addValue(key, value string) {
+	if value != "" {
+		tags[key] = append(tags[key], value)
+	}
+}
+
+// decodeInvolvedPeople adds the role and name pairs of a TIPL frame to
+// tags. Roles without a field are kept in a field named after the role.
+func decodeInvolvedPeople(tags Tags, values []string) {
+	fields := make(map[string]string)
+	for key, role := range id3InvolvedPeople {
+		fields[role] = key
+	}
+	for i := 0; i+1 < len(values); i += 2 {
+		key, ok := fields[strings.ToLower(values[i])]
+		if !ok {
+			key = strings.ToUpper(values[i])
+		}
+		tags.addValue(key, values[i+1])
+	}
+}
+
+// decodeMusicianCredits adds the instrument and name pairs of a TMCL
+// frame to tags as performers
+func decodeMusicianCredits(tags Tags, values []string) {
+	for i := 0; i+1 < len(values); i += 2 {
+		instrument, name := values[i], values[i+1]
+		if name != "" && instrument != "" && instrument != defaultInstrument {
+			name += " (" + instrument + ")"
+		}
+		tags.addValue(performerField, name)
+	}
+}
+
+// encodeInvolvedPeople creates a TIPL frame from the fields in
+// id3InvolvedPeople, marking them as used. ok is false if there are
+// none.
+func encodeInvolvedPeople(tags Tags, used map[string]bool) (frame id3Frame, ok bool) {
+	keys := make([]string, 0, len(id3InvolvedPeople))
+	for key := range id3InvolvedPeople {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		used[key] = true
+		for _, name := range tags[key] {
+			pairs = append(pairs, id3InvolvedPeople[key], name)
+		}
+	}
+	if len(pairs) == 0 {
+		return id3Frame{}, false
+	}
+	return encodeTextFrame("TIPL", pairs...), true
+}
+
+// encodeMusicianCredits creates a TMCL frame from the performers,
+// marking them as used. ok is false if there are none.
+func encodeMusicianCredits(tags Tags, used map[string]bool) (frame id3Frame, ok bool) {
+	used[performerField] = true
+	var pairs []string
+	for _, performer := range tags[performerField] {
+		instrument, name := defaultInstrument, performer
+		if match := performerInstrument.FindStringSubmatch(performer); match != nil {
+			name, instrument = match[1], match[2]
+		}
+		pairs = append(pairs, instrument, name)
+	}
+	if len(pairs) == 0 {
+		return id3Frame{}, false
+	}
+	return encodeTextFrame("TMCL", pairs...), true
+}
@@ -0,0 +1,225 @@
+package tags
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FLAC metadata block types
+const (
+	flacStreamInfo    = 0
+	flacPadding       = 1
+	flacVorbisComment = 4
+)
+
+const (
+	flacPaddingSize = 1024
+	flacVendor      = "deezerdl"
+)
+
+var ErrBadFLAC = errors.New("invalid FLAC metadata")
+
+// flacBlock is a single FLAC metadata block
+type flacBlock struct {
+	Type byte
+	Data []byte
+}
+
+// readFLACBlocks reads the magic number and all metadata blocks,
+// leaving r positioned at the first audio frame. The returned reader
+// must be used for the rest of the file.
+func readFLACBlocks(r io.Reader) ([]flacBlock, io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(flacMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, nil, err
+	}
+	if !isFLAC(magic) {
+		return nil, nil, ErrBadFLAC
+	}
+
+	var blocks []flacBlock
+	for last := false; !last; {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(br, header); err != nil {
+			return nil, nil, ErrBadFLAC
+		}
+		last = header[0]&0x80 != 0
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, nil, ErrBadFLAC
+		}
+		blocks = append(blocks, flacBlock{
+			Type: header[0] & 0x7f,
+			Data: data,
+		})
+	}
+
+	if len(blocks) == 0 || blocks[0].Type != flacStreamInfo {
+		return nil, nil, ErrBadFLAC
+	}
+	return blocks, br, nil
+}
+
+// flacTags finds the vorbis comment block and decodes it
+func flacTags(blocks []flacBlock) (Tags, error) {
+	for _, block := range blocks {
+		if block.Type == flacVorbisComment {
+			_, tags, err := decodeVorbisComment(block.Data)
+			return tags, err
+		}
+	}
+	return Tags{}, nil
+}
+
+// decodeVorbisComment decodes the vendor string and comments of a
+// vorbis comment block. All integers are little-endian.
+func decodeVorbisComment(data []byte) (string, Tags, error) {
+	tags := Tags{}
+
+	readString := func() (string, error) {
+		if len(data) < 4 {
+			return "", ErrBadFLAC
+		}
+		length := binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		if uint32(len(data)) < length {
+			return "", ErrBadFLAC
+		}
+		s := string(data[:length])
+		data = data[length:]
+		return s, nil
+	}
+
+	vendor, err := readString()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(data) < 4 {
+		return "", nil, ErrBadFLAC
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+
+	for i := uint32(0); i < count; i++ {
+		comment, err := readString()
+		if err != nil {
+			return "", nil, err
+		}
+		parts := strings.SplitN(comment, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToUpper(parts[0])
+		tags[key] = append(tags[key], parts[1])
+	}
+
+	return vendor, tags, nil
+}
+
+// encodeVorbisComment encodes a vorbis comment block
+func encodeVorbisComment(vendor string, tags Tags) []byte {
+	var buf []byte
+	putUint32 := func(n int) {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, uint32(n))
+		buf = append(buf, b...)
+	}
+
+	putUint32(len(vendor))
+	buf = append(buf, vendor...)
+
+	count := 0
+	for _, key := range tags.Keys() {
+		count += len(tags[key])
+	}
+	putUint32(count)
+
+	for _, key := range tags.Keys() {
+		for _, value := range tags[key] {
+			comment := key + "=" + value
+			putUint32(len(comment))
+			buf = append(buf, comment...)
+		}
+	}
+	return buf
+}
+
+// writeFLAC copies a FLAC file from r to w with the given tags merged
+// into its vorbis comment block
+func writeFLAC(w io.Writer, r io.Reader, tags Tags) error {
+	blocks, audio, err := readFLACBlocks(r)
+	if err != nil {
+		return err
+	}
+
+	// merge with the existing comments and drop the old comment and
+	// padding blocks
+	vendor := flacVendor
+	merged := Tags{}
+	var kept []flacBlock
+	for _, block := range blocks {
+		switch block.Type {
+		case flacVorbisComment:
+			var existing Tags
+			vendor, existing, err = decodeVorbisComment(block.Data)
+			if err != nil {
+				return err
+			}
+			for key, values := range existing {
+				merged[key] = values
+			}
+		case flacPadding:
+		default:
+			kept = append(kept, block)
+		}
+	}
+	for key, values := range tags {
+		merged.Set(key, values...)
+	}
+
+	comment := encodeVorbisComment(vendor, merged)
+	if len(comment) >= 1<<24 {
+		return fmt.Errorf("vorbis comment too large: %d bytes", len(comment))
+	}
+
+	// STREAMINFO must stay first, so the comment goes after the kept
+	// blocks and padding goes last
+	kept = append(kept,
+		flacBlock{Type: flacVorbisComment, Data: comment},
+		flacBlock{Type: flacPadding, Data: make([]byte, flacPaddingSize)},
+	)
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(flacMagic); err != nil {
+		return err
+	}
+	for i, block := range kept {
+		header := []byte{
+			block.Type,
+			byte(len(block.Data) >> 16),
+			byte(len(block.Data) >> 8),
+			byte(len(block.Data)),
+		}
+		if i == len(kept)-1 {
+			header[0] |= 0x80
+		}
+		if _, err := bw.Write(header); err != nil {
+			return err
+		}
+		if _, err := bw.Write(block.Data); err != nil {
+			return err
+		}
+	}
+	if _, err := io.Copy(bw, audio); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
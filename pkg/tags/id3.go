@@ -0,0 +1,426 @@
+package tags
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+const id3PaddingSize = 1024
+
+// ID3v2 text encodings
+const (
+	id3Latin1  = 0
+	id3UTF16   = 1
+	id3UTF16BE = 2
+	id3UTF8    = 3
+)
+
+var ErrBadID3 = errors.New("invalid ID3 tag")
+
+// id3TextFrames maps field names to the ID3v2.4 text frames that store
+// them. Fields that are not listed here are stored in TXXX frames.
+var id3TextFrames = map[string]string{
+	"TITLE":       "TIT2",
+	"ARTIST":      "TPE1",
+	"ALBUM":       "TALB",
+	"ALBUMARTIST": "TPE2",
+	"DATE":        "TDRC",
+	"GENRE":       "TCON",
+	"ISRC":        "TSRC",
+	"LABEL":       "TPUB",
+	"COPYRIGHT":   "TCOP",
+	"BPM":         "TBPM",
+	"COMPOSER":    "TCOM",
+	"LYRICIST":    "TEXT",
+}
+
+// id3InvolvedPeople maps field names to the roles they are stored
+// under in the TIPL frame
+var id3InvolvedPeople = map[string]string{
+	"PRODUCER": "producer",
+	"MIXER":    "mix",
+	"ENGINEER": "engineer",
+	"ARRANGER": "arranger",
+}
+
+// performerField is stored in the TMCL frame. Values may give the
+// instrument in brackets, e.g. "Nile Rodgers (guitar)", as is usual in
+// Vorbis comments.
+const performerField = "PERFORMER"
+
+// defaultInstrument is the TMCL role of performers without an
+// instrument
+const defaultInstrument = "performer"
+
+var performerInstrument = regexp.MustCompile(`^(.*\S)\s*\(([^()]+)\)$`)
+
+// id3NumberFrames maps the number/total text frames to the pair of
+// fields they store
+var id3NumberFrames = map[string][2]string{
+	"TRCK": {"TRACKNUMBER", "TOTALTRACKS"},
+	"TPOS": {"DISCNUMBER", "TOTALDISCS"},
+}
+
+// id3Frame is a single raw ID3v2 frame
+type id3Frame struct {
+	ID   string
+	Data []byte
+}
+
+// id3Tag stores the frames of an ID3v2 tag
+type id3Tag struct {
+	Frames []id3Frame
+}
+
+// syncsafe decodes a 28-bit syncsafe integer
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// putSyncsafe encodes a 28-bit syncsafe integer
+func putSyncsafe(n int) []byte {
+	return []byte{
+		byte(n>>21) & 0x7f,
+		byte(n>>14) & 0x7f,
+		byte(n>>7) & 0x7f,
+		byte(n) & 0x7f,
+	}
+}
+
+// readID3WithAudio reads the ID3v2 tag at the start of r, if there is
+// one. The returned reader continues from the first byte after the tag
+// and must be used for the rest of the file. Tags that cannot be
+// understood (ID3v2.2, or unsynchronised tags) are treated as empty.
+func readID3WithAudio(r io.Reader) (*id3Tag, io.Reader, error) {
+	br := bufio.NewReader(r)
+	tag := &id3Tag{}
+
+	header, err := br.Peek(10)
+	if err != nil || !bytes.HasPrefix(header, []byte("ID3")) {
+		// no tag
+		return tag, br, nil
+	}
+	if _, err := br.Discard(10); err != nil {
+		return nil, nil, err
+	}
+
+	major := header[3]
+	flags := header[5]
+	body := make([]byte, syncsafe(header[6:10]))
+	if _, err := io.ReadFull(br, body); err != nil {
+		return nil, nil, ErrBadID3
+	}
+	if flags&0x10 != 0 {
+		// skip the footer
+		if _, err := br.Discard(10); err != nil {
+			return nil, nil, ErrBadID3
+		}
+	}
+
+	if (major != 3 && major != 4) || flags&0x80 != 0 {
+		return tag, br, nil
+	}
+
+	// skip the extended header
+	if flags&0x40 != 0 {
+		if len(body) < 4 {
+			return nil, nil, ErrBadID3
+		}
+		var size int
+		if major == 4 {
+			size = syncsafe(body)
+		} else {
+			size = int(binary.BigEndian.Uint32(body)) + 4
+		}
+		if size > len(body) {
+			return nil, nil, ErrBadID3
+		}
+		body = body[size:]
+	}
+
+	for len(body) >= 10 && body[0] != 0 {
+		id := string(body[:4])
+		var size int
+		if major == 4 {
+			size = syncsafe(body[4:8])
+		} else {
+			size = int(binary.BigEndian.Uint32(body[4:8]))
+		}
+		frameFlags := binary.BigEndian.Uint16(body[8:10])
+		body = body[10:]
+		if size > len(body) {
+			return nil, nil, ErrBadID3
+		}
+		data := body[:size]
+		body = body[size:]
+
+		// drop compressed, encrypted or otherwise transformed frames,
+		// since their data cannot be copied as-is
+		if (major == 3 && frameFlags&0x00c0 != 0) || (major == 4 && frameFlags&0x000f != 0) {
+			continue
+		}
+		tag.Frames = append(tag.Frames, id3Frame{ID: id, Data: data})
+	}
+
+	return tag, br, nil
+}
+
+// readID3 reads the ID3v2 tag at the start of r
+func readID3(r io.Reader) (*id3Tag, error) {
+	tag, _, err := readID3WithAudio(r)
+	return tag, err
+}
+
+// decodeLatin1 converts ISO-8859-1 to a string
+func decodeLatin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// decodeUTF16 converts UTF-16 to a string, using the byte order mark
+// if there is one
+func decodeUTF16(data []byte, bigEndian bool) string {
+	if len(data) >= 2 {
+		if data[0] == 0xff && data[1] == 0xfe {
+			bigEndian = false
+			data = data[2:]
+		} else if data[0] == 0xfe && data[1] == 0xff {
+			bigEndian = true
+			data = data[2:]
+		}
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = binary.BigEndian.Uint16(data[2*i:])
+		} else {
+			units[i] = binary.LittleEndian.Uint16(data[2*i:])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// splitID3Strings splits null-terminated strings in the given encoding
+func splitID3Strings(encoding byte, data []byte) []string {
+	var values []string
+	if encoding == id3UTF16 || encoding == id3UTF16BE {
+		start := 0
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				values = append(values, decodeUTF16(data[start:i], encoding == id3UTF16BE))
+				start = i + 2
+			}
+		}
+		if start < len(data) {
+			values = append(values, decodeUTF16(data[start:], encoding == id3UTF16BE))
+		}
+		return values
+	}
+
+	for _, part := range bytes.Split(data, []byte{0}) {
+		if encoding == id3Latin1 {
+			values = append(values, decodeLatin1(part))
+		} else {
+			values = append(values, string(part))
+		}
+	}
+	// drop the empty string after a trailing terminator
+	if len(values) > 1 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	return values
+}
+
+// textValues decodes the strings of a text frame
+func (frame *id3Frame) textValues() []string {
+	if len(frame.Data) == 0 {
+		return nil
+	}
+	return splitID3Strings(frame.Data[0], frame.Data[1:])
+}
+
+// encodeTextFrame creates a UTF-8 text frame. For TXXX frames, the
+// first value is the description.
+func encodeTextFrame(id string, values ...string) id3Frame {
+	data := []byte{id3UTF8}
+	data = append(data, strings.Join(values, "\x00")...)
+	return id3Frame{ID: id, Data: data}
+}
+
+// isOwnedFrame reports whether a frame is generated from Tags when
+// writing, rather than copied from the existing tag
+func isOwnedFrame(id string) bool {
+	switch id {
+	case "TXXX", "TYER", "USLT", "SYLT", "TIPL", "TMCL":
+		return true
+	}
+	if _, ok := id3NumberFrames[id]; ok {
+		return true
+	}
+	for _, frameID := range id3TextFrames {
+		if frameID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Tags converts the frames that have a field name to Tags
+func (tag *id3Tag) Tags() Tags {
+	tags := Tags{}
+	fieldNames := make(map[string]string)
+	for key, id := range id3TextFrames {
+		fieldNames[id] = key
+	}
+	fieldNames["TYER"] = "DATE"
+
+	for _, frame := range tag.Frames {
+		switch frame.ID {
+		case "USLT":
+			tags.Set(UnsyncedLyricsField, decodeUSLT(frame.Data))
+			continue
+		case "SYLT":
+			tags.Set(LyricsField, formatLRC(decodeSYLT(frame.Data)))
+			continue
+		case "TIPL":
+			decodeInvolvedPeople(tags, frame.textValues())
+			continue
+		case "TMCL":
+			decodeMusicianCredits(tags, frame.textValues())
+			continue
+		}
+
+		values := frame.textValues()
+		if len(values) == 0 {
+			continue
+		}
+
+		if key, ok := fieldNames[frame.ID]; ok {
+			tags.Set(key, values...)
+		} else if keys, ok := id3NumberFrames[frame.ID]; ok {
+			parts := strings.SplitN(values[0], "/", 2)
+			tags.Set(keys[0], parts[0])
+			if len(parts) == 2 {
+				tags.Set(keys[1], parts[1])
+			}
+		} else if frame.ID == "TXXX" && len(values) >= 2 {
+			tags.Set(values[0], values[1:]...)
+		}
+	}
+	return tags
+}
+
+// setTags replaces all frames that can be generated from Tags with
+// frames generated from tags, keeping everything else
+func (tag *id3Tag) setTags(tags Tags) {
+	var frames []id3Frame
+	for _, frame := range tag.Frames {
+		if !isOwnedFrame(frame.ID) {
+			frames = append(frames, frame)
+		}
+	}
+
+	// frames are added in a fixed order so that the same tags always
+	// give the same file
+	keys := make([]string, 0, len(id3TextFrames))
+	for key := range id3TextFrames {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	used := make(map[string]bool)
+	for _, key := range keys {
+		if values, ok := tags[key]; ok {
+			frames = append(frames, encodeTextFrame(id3TextFrames[key], values...))
+			used[key] = true
+		}
+	}
+	for _, id := range []string{"TRCK", "TPOS"} {
+		keys := id3NumberFrames[id]
+		number, total := tags.Get(keys[0]), tags.Get(keys[1])
+		used[keys[0]], used[keys[1]] = true, true
+		if number == "" {
+			continue
+		}
+		if total != "" {
+			number += "/" + total
+		}
+		frames = append(frames, encodeTextFrame(id, number))
+	}
+	// lyrics without timestamps can only go in USLT
+	text := tags.Get(UnsyncedLyricsField)
+	lines := parseLRC(tags.Get(LyricsField))
+	if len(lines) == 0 && text == "" {
+		text = tags.Get(LyricsField)
+	}
+	if text != "" {
+		frames = append(frames, encodeUSLT(text))
+	}
+	if len(lines) > 0 {
+		frames = append(frames, encodeSYLT(lines))
+	}
+	used[UnsyncedLyricsField], used[LyricsField] = true, true
+	if frame, ok := encodeInvolvedPeople(tags, used); ok {
+		frames = append(frames, frame)
+	}
+	if frame, ok := encodeMusicianCredits(tags, used); ok {
+		frames = append(frames, frame)
+	}
+	for _, key := range tags.Keys() {
+		if !used[key] {
+			frames = append(frames, encodeTextFrame("TXXX", append([]string{key}, tags[key]...)...))
+		}
+	}
+
+	tag.Frames = frames
+}
+
+// encode encodes the tag as ID3v2.4 with padding
+func (tag *id3Tag) encode() []byte {
+	var body []byte
+	for _, frame := range tag.Frames {
+		body = append(body, frame.ID...)
+		body = append(body, putSyncsafe(len(frame.Data))...)
+		body = append(body, 0, 0)
+		body = append(body, frame.Data...)
+	}
+	body = append(body, make([]byte, id3PaddingSize)...)
+
+	header := []byte{'I', 'D', '3', 4, 0, 0}
+	header = append(header, putSyncsafe(len(body))...)
+	return append(header, body...)
+}
+
+// writeMP3 copies an MP3 file from r to w with the given tags merged
+// into its ID3v2 tag, creating one if needed
+func writeMP3(w io.Writer, r io.Reader, tags Tags) error {
+	tag, audio, err := readID3WithAudio(r)
+	if err != nil {
+		return err
+	}
+
+	merged := tag.Tags()
+	for key, values := range tags {
+		merged.Set(key, values...)
+	}
+	tag.setTags(merged)
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(tag.encode()); err != nil {
+		return err
+	}
+	if _, err := io.Copy(bw, audio); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,161 @@
+package tags
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshbarrass/deezerdl/pkg/lrc"
+)
+
+// Lyrics fields. LYRICS holds synchronised lyrics in LRC format and
+// UNSYNCEDLYRICS holds plain text, as most players expect in Vorbis
+// comments. In ID3 tags they are stored in SYLT and USLT frames.
+const (
+	LyricsField         = "LYRICS"
+	UnsyncedLyricsField = "UNSYNCEDLYRICS"
+)
+
+// id3UnknownLanguage is the ISO-639-2 code for an unknown language
+const id3UnknownLanguage = "XXX"
+
+// SYLT frame settings
+const (
+	syltMilliseconds = 2
+	syltLyrics       = 1
+)
+
+// lrcLine matches a line of an LRC file with one or more timestamps
+var lrcLine = regexp.MustCompile(`^((?:\[\d+:\d{2}(?:[.:]\d{1,3})?\])+)(.*)$`)
+var lrcTimestamp = regexp.MustCompile(`\[(\d+):(\d{2})(?:[.:](\d{1,3}))?\]`)
+
+// syncedLine is a line of synchronised lyrics, with its time in
+// milliseconds
+type syncedLine struct {
+	Time uint32
+	Text string
+}
+
+// parseLRC reads the timed lines of an LRC file, skipping ID tags such
+// as [ar:...]
+func parseLRC(lrc string) []syncedLine {
+	var lines []syncedLine
+	scanner := bufio.NewScanner(strings.NewReader(lrc))
+	for scanner.Scan() {
+		match := lrcLine.FindStringSubmatch(strings.TrimRight(scanner.Text(), "\r"))
+		if match == nil {
+			continue
+		}
+		for _, stamp := range lrcTimestamp.FindAllStringSubmatch(match[1], -1) {
+			minutes, _ := strconv.Atoi(stamp[1])
+			seconds, _ := strconv.Atoi(stamp[2])
+			// the fraction may be tenths, hundredths or thousandths
+			fraction := stamp[3]
+			millis, _ := strconv.Atoi((fraction + "000")[:3])
+			lines = append(lines, syncedLine{
+				Time: uint32((minutes*60+seconds)*1000 + millis),
+				Text: match[2],
+			})
+		}
+	}
+	return lines
+}
+
+// formatLRC writes timed lines as an LRC file
+func formatLRC(lines []syncedLine) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(lrc.FormatTime(time.Duration(line.Time) * time.Millisecond))
+		b.WriteString(line.Text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// readID3String reads a null-terminated string in the given encoding,
+// returning it and the data after the terminator
+func readID3String(encoding byte, data []byte) (string, []byte) {
+	if encoding == id3UTF16 || encoding == id3UTF16BE {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return decodeUTF16(data[:i], encoding == id3UTF16BE), data[i+2:]
+			}
+		}
+		return decodeUTF16(data, encoding == id3UTF16BE), nil
+	}
+
+	end := bytes.IndexByte(data, 0)
+	rest := []byte(nil)
+	if end < 0 {
+		end = len(data)
+	} else {
+		rest = data[end+1:]
+	}
+	if encoding == id3Latin1 {
+		return decodeLatin1(data[:end]), rest
+	}
+	return string(data[:end]), rest
+}
+
+// encodeUSLT creates a UTF-8 unsynchronised lyrics frame
+func encodeUSLT(text string) id3Frame {
+	data := []byte{id3UTF8}
+	data = append(data, id3UnknownLanguage...)
+	data = append(data, 0) // empty description
+	data = append(data, text...)
+	return id3Frame{ID: "USLT", Data: data}
+}
+
+// decodeUSLT returns the text of an unsynchronised lyrics frame
+func decodeUSLT(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	_, rest := readID3String(data[0], data[4:])
+	text, _ := readID3String(data[0], rest)
+	return text
+}
+
+// encodeSYLT creates a UTF-8 synchronised lyrics frame with times in
+// milliseconds
+func encodeSYLT(lines []syncedLine) id3Frame {
+	data := []byte{id3UTF8}
+	data = append(data, id3UnknownLanguage...)
+	data = append(data, syltMilliseconds, syltLyrics, 0) // empty description
+	for _, line := range lines {
+		data = append(data, line.Text...)
+		data = append(data, 0)
+		data = append(data, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(data[len(data)-4:], line.Time)
+	}
+	return id3Frame{ID: "SYLT", Data: data}
+}
+
+// decodeSYLT returns the lines of a synchronised lyrics frame, or nil
+// if its times are not in milliseconds
+func decodeSYLT(data []byte) []syncedLine {
+	if len(data) < 6 || data[4] != syltMilliseconds {
+		return nil
+	}
+	encoding := data[0]
+	_, rest := readID3String(encoding, data[6:])
+
+	var lines []syncedLine
+	for len(rest) > 0 {
+		var text string
+		text, rest = readID3String(encoding, rest)
+		if len(rest) < 4 {
+			break
+		}
+		lines = append(lines, syncedLine{
+			Time: binary.BigEndian.Uint32(rest),
+			Text: text,
+		})
+		rest = rest[4:]
+	}
+	return lines
+}
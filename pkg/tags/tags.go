@@ -0,0 +1,163 @@
+// Package tags reads and writes the metadata tags of the FLAC and MP3
+// files that Deezer serves. Tags are named using Vorbis comment field
+// names (TITLE, TRACKNUMBER, ...) and are translated to the matching
+// ID3v2 frames for MP3 files.
+package tags
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var ErrUnknownFormat = errors.New("unknown audio file format")
+
+var flacMagic = []byte("fLaC")
+
+// Tags maps upper-case field names to their values. A field can have
+// more than one value.
+type Tags map[string][]string
+
+// Set replaces the values of a field. Empty values are skipped, and
+// setting no values removes the field.
+func (tags Tags) Set(key string, values ...string) {
+	key = strings.ToUpper(key)
+	var kept []string
+	for _, v := range values {
+		if v != "" {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == 0 {
+		delete(tags, key)
+		return
+	}
+	tags[key] = kept
+}
+
+// Get returns the first value of a field, or an empty string
+func (tags Tags) Get(key string) string {
+	values := tags[strings.ToUpper(key)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Keys returns the field names in sorted order
+func (tags Tags) Keys() []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isFLAC checks the magic number at the start of the data
+func isFLAC(data []byte) bool {
+	return bytes.HasPrefix(data, flacMagic)
+}
+
+// isMP3 checks for an ID3v2 tag or an MPEG frame sync at the start of
+// the data
+func isMP3(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("ID3")) {
+		return true
+	}
+	return len(data) >= 2 && data[0] == 0xff && data[1]&0xe0 == 0xe0
+}
+
+// ReadFile reads the tags from a FLAC or MP3 file
+func ReadFile(path string) (Tags, error) {
+	inFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer inFile.Close()
+
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(inFile, header); err != nil {
+		return nil, ErrUnknownFormat
+	}
+	if _, err := inFile.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isFLAC(header):
+		blocks, _, err := readFLACBlocks(inFile)
+		if err != nil {
+			return nil, err
+		}
+		return flacTags(blocks)
+	case isMP3(header):
+		tag, err := readID3(inFile)
+		if err != nil {
+			return nil, err
+		}
+		return tag.Tags(), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// WriteFile merges the given tags into the existing tags of a FLAC or
+// MP3 file. Fields in tags replace any existing values of the same
+// field; all other existing fields are kept. The file is rewritten
+// through a temporary file so that it is never left half-written.
+func WriteFile(path string, tags Tags) error {
+	inFile, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(inFile, header); err != nil {
+		return ErrUnknownFormat
+	}
+	if _, err := inFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	info, err := inFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tags")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	switch {
+	case isFLAC(header):
+		err = writeFLAC(tmpFile, inFile, tags)
+	case isMP3(header):
+		err = writeMP3(tmpFile, inFile, tags)
+	default:
+		err = ErrUnknownFormat
+	}
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(info.Mode().Perm()); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	inFile.Close()
+
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,241 @@
+package tags
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testAudio stands in for the audio frames after the metadata
+var testAudio = bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+
+// testFLAC builds a FLAC file with an empty STREAMINFO block
+func testFLAC() []byte {
+	data := []byte("fLaC")
+	data = append(data, 0x80, 0, 0, 34)
+	data = append(data, make([]byte, 34)...)
+	return append(data, testAudio...)
+}
+
+// testMP3 builds an MP3 file with an ID3v2.3 tag containing a title and
+// a frame that is not generated from Tags
+func testMP3() []byte {
+	var body []byte
+	for _, frame := range []id3Frame{
+		{ID: "TIT2", Data: append([]byte{id3Latin1}, "Old Title"...)},
+		{ID: "TSSE", Data: append([]byte{id3Latin1}, "encoder"...)},
+	} {
+		body = append(body, frame.ID...)
+		body = append(body, 0, 0, 0, byte(len(frame.Data)), 0, 0)
+		body = append(body, frame.Data...)
+	}
+	data := []byte{'I', 'D', '3', 3, 0, 0}
+	data = append(data, putSyncsafe(len(body))...)
+	data = append(data, body...)
+	return append(data, testAudio...)
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) (string, func()) {
+	dir, err := ioutil.TempDir("", "deezerdl-tags")
+	assert.Equal(t, nil, err)
+	path := filepath.Join(dir, name)
+	assert.Equal(t, nil, ioutil.WriteFile(path, data, 0644))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestTagsRoundTrip(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"test.flac": testFLAC(),
+		"test.mp3":  testMP3(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			path, cleanup := writeTestFile(t, name, data)
+			defer cleanup()
+
+			tags := Tags{}
+			tags.Set("TITLE", "One More Time")
+			tags.Set("TRACKNUMBER", "1")
+			tags.Set("TOTALTRACKS", "14")
+			tags.Set("DEEZER_SNG_ID", "3135553")
+			assert.Equal(t, nil, WriteFile(path, tags))
+
+			result, err := ReadFile(path)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, "One More Time", result.Get("TITLE"))
+			assert.Equal(t, "1", result.Get("TRACKNUMBER"))
+			assert.Equal(t, "14", result.Get("TOTALTRACKS"))
+			assert.Equal(t, "3135553", result.Get("DEEZER_SNG_ID"))
+
+			// writing again replaces rather than duplicates
+			tags = Tags{}
+			tags.Set("TITLE", "Aerodynamic")
+			assert.Equal(t, nil, WriteFile(path, tags))
+			result, err = ReadFile(path)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, []string{"Aerodynamic"}, result["TITLE"])
+			assert.Equal(t, "3135553", result.Get("DEEZER_SNG_ID"))
+
+			// the audio must be untouched
+			written, err := ioutil.ReadFile(path)
+			assert.Equal(t, nil, err)
+			assert.True(t, bytes.HasSuffix(written, testAudio))
+		})
+	}
+}
+
+func TestID3KeepsUnknownFrames(t *testing.T) {
+	path, cleanup := writeTestFile(t, "test.mp3", testMP3())
+	defer cleanup()
+
+	tags := Tags{}
+	tags.Set("ARTIST", "Daft Punk")
+	assert.Equal(t, nil, WriteFile(path, tags))
+
+	inFile, err := os.Open(path)
+	assert.Equal(t, nil, err)
+	defer inFile.Close()
+	tag, err := readID3(inFile)
+	assert.Equal(t, nil, err)
+
+	ids := make(map[string]bool)
+	for _, frame := range tag.Frames {
+		ids[frame.ID] = true
+	}
+	assert.True(t, ids["TSSE"], "unknown frames should be kept")
+	assert.Equal(t, "Old Title", tag.Tags().Get("TITLE"))
+	assert.Equal(t, "Daft Punk", tag.Tags().Get("ARTIST"))
+}
+
+func TestID3Deterministic(t *testing.T) {
+	tags := Tags{}
+	tags.Set("TITLE", "One More Time")
+	tags.Set("ARTIST", "Daft Punk")
+	tags.Set("ALBUM", "Discovery")
+	tags.Set("ALBUMARTIST", "Daft Punk")
+	tags.Set("GENRE", "Electro")
+	tags.Set("DATE", "2001")
+
+	// the same tags must always give the same file, or the archive's
+	// checksums would change on every download
+	var first []byte
+	for i := 0; i < 10; i++ {
+		var buf bytes.Buffer
+		assert.Equal(t, nil, writeMP3(&buf, bytes.NewReader(testMP3()), tags))
+		if first == nil {
+			first = buf.Bytes()
+		}
+		assert.Equal(t, first, buf.Bytes())
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	path, cleanup := writeTestFile(t, "test.txt", []byte("not an audio file"))
+	defer cleanup()
+
+	_, err := ReadFile(path)
+	assert.Equal(t, ErrUnknownFormat, err)
+	assert.Equal(t, ErrUnknownFormat, WriteFile(path, Tags{}))
+}
+
+func TestLyricsRoundTrip(t *testing.T) {
+	const (
+		lrc  = "[00:03.58]One more time\n[01:02.30]We're gonna celebrate\n"
+		text = "One more time\nWe're gonna celebrate"
+	)
+	for name, data := range map[string][]byte{
+		"test.flac": testFLAC(),
+		"test.mp3":  testMP3(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			path, cleanup := writeTestFile(t, name, data)
+			defer cleanup()
+
+			tags := Tags{}
+			tags.Set(LyricsField, lrc)
+			tags.Set(UnsyncedLyricsField, text)
+			assert.Equal(t, nil, WriteFile(path, tags))
+
+			result, err := ReadFile(path)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, lrc, result.Get(LyricsField))
+			assert.Equal(t, text, result.Get(UnsyncedLyricsField))
+		})
+	}
+}
+
+func TestParseLRC(t *testing.T) {
+	lines := parseLRC("[ar:Daft Punk]\n[00:03.5]One more time\r\n[00:10.123][01:00.00]Repeat\nnot a line\n")
+	assert.Equal(t, []syncedLine{
+		{Time: 3500, Text: "One more time"},
+		{Time: 10123, Text: "Repeat"},
+		{Time: 60000, Text: "Repeat"},
+	}, lines)
+	assert.Equal(t, "[00:03.50]One more time\n[00:10.12]Repeat\n[01:00.00]Repeat\n", formatLRC(lines))
+}
+
+func TestSYLT(t *testing.T) {
+	lines := []syncedLine{{Time: 3580, Text: "One more time"}, {Time: 62300, Text: "ünïcode"}}
+	frame := encodeSYLT(lines)
+	assert.Equal(t, lines, decodeSYLT(frame.Data))
+
+	// times in MPEG frames are not understood
+	frame.Data[4] = 1
+	assert.Equal(t, 0, len(decodeSYLT(frame.Data)))
+}
+
+func TestCreditsRoundTrip(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"test.flac": testFLAC(),
+		"test.mp3":  testMP3(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			path, cleanup := writeTestFile(t, name, data)
+			defer cleanup()
+
+			tags := Tags{}
+			tags.Set("COMPOSER", "Thomas Bangalter", "Guy-Manuel de Homem-Christo")
+			tags.Set("LYRICIST", "Anthony Moore")
+			tags.Set("PRODUCER", "Daft Punk")
+			tags.Set("MIXER", "Thomas Bangalter")
+			tags.Set("PERFORMER", "Romanthony", "Nile Rodgers (guitar)")
+			assert.Equal(t, nil, WriteFile(path, tags))
+
+			result, err := ReadFile(path)
+			assert.Equal(t, nil, err)
+			for _, key := range tags.Keys() {
+				assert.Equal(t, tags[key], result[key], key)
+			}
+		})
+	}
+}
+
+func TestID3Credits(t *testing.T) {
+	tag := &id3Tag{}
+	tags := Tags{}
+	tags.Set("COMPOSER", "Thomas Bangalter")
+	tags.Set("LYRICIST", "Anthony Moore")
+	tags.Set("PRODUCER", "Daft Punk")
+	tags.Set("PERFORMER", "Romanthony", "Nile Rodgers (guitar)")
+	tag.setTags(tags)
+
+	frames := make(map[string][]string)
+	for _, frame := range tag.Frames {
+		frames[frame.ID] = frame.textValues()
+	}
+	assert.Equal(t, []string{"Thomas Bangalter"}, frames["TCOM"])
+	assert.Equal(t, []string{"Anthony Moore"}, frames["TEXT"])
+	assert.Equal(t, []string{"producer", "Daft Punk"}, frames["TIPL"])
+	assert.Equal(t, []string{"performer", "Romanthony", "guitar", "Nile Rodgers"}, frames["TMCL"])
+	_, ok := frames["TXXX"]
+	assert.False(t, ok, "credits should not be stored in TXXX frames")
+
+	// roles without a field are kept
+	tag.Frames = []id3Frame{encodeTextFrame("TIPL", "DJ-mix", "Todd Edwards", "mix", "Daft Punk")}
+	result := tag.Tags()
+	assert.Equal(t, "Todd Edwards", result.Get("DJ-MIX"))
+	assert.Equal(t, "Daft Punk", result.Get("MIXER"))
+}
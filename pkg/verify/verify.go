@@ -0,0 +1,247 @@
+// Package verify checks that downloaded and decrypted songs are
+// complete, well-formed audio files, so that corrupt downloads can be
+// detected and retried rather than silently kept.
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mewkiz/flac"
+)
+
+var (
+	ErrSizeMismatch = errors.New("file size does not match")
+	ErrNoFrameSync  = errors.New("no MPEG frame sync")
+	ErrTruncated    = errors.New("file is truncated")
+	ErrBadStream    = errors.New("invalid FLAC stream")
+	ErrMD5Mismatch  = errors.New("decoded audio does not match the FLAC MD5 signature")
+)
+
+// Size checks that the file at path is exactly size bytes long. A
+// size of 0 or less means the expected size is unknown, and always
+// passes.
+func Size(path string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != size {
+		return fmt.Errorf("%w: expected %d bytes, got %d", ErrSizeMismatch, size, info.Size())
+	}
+	return nil
+}
+
+// FLACFile verifies the FLAC file at path. See FLAC.
+func FLACFile(path string) error {
+	inFile, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+	return FLAC(bufio.NewReader(inFile))
+}
+
+// FLAC decodes a whole FLAC stream, checking the STREAMINFO block, the
+// CRC of every frame and the sample count. If the encoder recorded an
+// MD5 signature of the audio, it is compared with the decoded samples.
+func FLAC(r io.Reader) error {
+	stream, err := flac.New(r)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBadStream, err)
+	}
+	info := stream.Info
+	if info.SampleRate == 0 || info.NChannels == 0 || info.BitsPerSample == 0 {
+		return fmt.Errorf("%w: bad STREAMINFO", ErrBadStream)
+	}
+
+	hash := md5.New()
+	var samples uint64
+	frames := 0
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%w: frame %d: %s", ErrBadStream, frames, err)
+		}
+		frame.Hash(hash)
+		samples += uint64(frame.BlockSize)
+		frames++
+	}
+	if frames == 0 {
+		return fmt.Errorf("%w: no audio frames", ErrBadStream)
+	}
+	if info.NSamples != 0 && samples != info.NSamples {
+		return fmt.Errorf("%w: expected %d samples, got %d", ErrTruncated, info.NSamples, samples)
+	}
+
+	var unset [md5.Size]byte
+	if info.MD5sum != unset && !bytes.Equal(hash.Sum(nil), info.MD5sum[:]) {
+		return ErrMD5Mismatch
+	}
+	return nil
+}
+
+// MP3File verifies the MP3 file at path. See MP3.
+func MP3File(path string) error {
+	inFile, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+	return MP3(inFile)
+}
+
+// MP3 walks every MPEG audio frame in the stream, after skipping any
+// leading ID3v2 tag, and checks that each frame header is valid and
+// that the last frame is complete. Trailing ID3v1, APE and Lyrics3
+// tags are allowed.
+func MP3(r io.Reader) error {
+	br := bufio.NewReader(r)
+	offset, err := skipID3v2(br)
+	if err != nil {
+		return err
+	}
+
+	frames := 0
+	for {
+		header, err := br.Peek(4)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(header) == 0 {
+			break
+		}
+		if isTrailingTag(br) {
+			break
+		}
+		if len(header) < 4 {
+			return fmt.Errorf("%w: partial frame header at offset %d", ErrTruncated, offset)
+		}
+
+		length, ok := mpegFrameLength(header)
+		if !ok {
+			return fmt.Errorf("%w at offset %d", ErrNoFrameSync, offset)
+		}
+		if length == 0 {
+			// free format: the frame length can't be worked out from
+			// the header, so stop checking here
+			break
+		}
+
+		n, err := br.Discard(length)
+		offset += int64(n)
+		if n < length {
+			return fmt.Errorf("%w: frame at offset %d is %d bytes short", ErrTruncated, offset-int64(n), length-n)
+		} else if err != nil {
+			return err
+		}
+		frames++
+	}
+
+	if frames == 0 {
+		return fmt.Errorf("%w: no audio frames", ErrNoFrameSync)
+	}
+	return nil
+}
+
+// skipID3v2 skips an ID3v2 tag at the start of the stream, returning
+// the number of bytes skipped
+func skipID3v2(br *bufio.Reader) (int64, error) {
+	header, err := br.Peek(10)
+	if err != nil || !bytes.HasPrefix(header, []byte("ID3")) {
+		// too short to be anything, let the frame check report it
+		return 0, nil
+	}
+	size := int(header[6]&0x7f)<<21 | int(header[7]&0x7f)<<14 | int(header[8]&0x7f)<<7 | int(header[9]&0x7f)
+	size += 10
+	if header[5]&0x10 != 0 {
+		// footer present
+		size += 10
+	}
+	n, err := br.Discard(size)
+	if n < size {
+		return int64(n), fmt.Errorf("%w: ID3v2 tag", ErrTruncated)
+	}
+	return int64(n), err
+}
+
+// isTrailingTag checks whether the rest of the stream starts with a
+// tag that can follow the audio frames
+func isTrailingTag(br *bufio.Reader) bool {
+	peek, _ := br.Peek(11)
+	return bytes.HasPrefix(peek, []byte("TAG")) ||
+		bytes.HasPrefix(peek, []byte("APETAGEX")) ||
+		bytes.HasPrefix(peek, []byte("LYRICSBEGIN"))
+}
+
+// bitrates in kbit/s, indexed by [MPEG-1 or not][layer - 1][index]
+var mpegBitrates = [2][3][15]int{
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	},
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	},
+}
+
+// sample rates in Hz for MPEG-1, indexed by the header field. MPEG-2
+// halves them and MPEG-2.5 quarters them.
+var mpegSampleRates = [3]int{44100, 48000, 32000}
+
+// mpegFrameLength parses a 4 byte MPEG audio frame header and returns
+// the length of the frame including the header. A length of 0 means a
+// free format frame. ok is false if the header is not valid.
+func mpegFrameLength(header []byte) (length int, ok bool) {
+	if header[0] != 0xff || header[1]&0xe0 != 0xe0 {
+		return 0, false
+	}
+	version := header[1] >> 3 & 0x03 // 0 = 2.5, 1 = reserved, 2 = 2, 3 = 1
+	layerBits := header[1] >> 1 & 0x03
+	bitrateIndex := header[2] >> 4
+	rateIndex := header[2] >> 2 & 0x03
+	padding := int(header[2] >> 1 & 0x01)
+	if version == 1 || layerBits == 0 || bitrateIndex == 0x0f || rateIndex == 0x03 {
+		return 0, false
+	}
+	if bitrateIndex == 0 {
+		return 0, true
+	}
+
+	layer := 4 - int(layerBits)
+	mpeg1 := version == 3
+	table := 0
+	if !mpeg1 {
+		table = 1
+	}
+	bitrate := mpegBitrates[table][layer-1][bitrateIndex] * 1000
+	sampleRate := mpegSampleRates[rateIndex]
+	switch version {
+	case 2:
+		sampleRate /= 2
+	case 0:
+		sampleRate /= 4
+	}
+
+	switch {
+	case layer == 1:
+		return (12*bitrate/sampleRate + padding) * 4, true
+	case layer == 3 && !mpeg1:
+		return 72*bitrate/sampleRate + padding, true
+	default:
+		return 144*bitrate/sampleRate + padding, true
+	}
+}
@@ -0,0 +1,169 @@
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+	"github.com/stretchr/testify/assert"
+)
+
+// mp3Frame is the header of an MPEG-1 layer III frame at 128 kbit/s
+// and 44.1 kHz, which is 417 bytes long
+var mp3Frame = []byte{0xff, 0xfb, 0x90, 0x00}
+
+func makeMP3(frames int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < frames; i++ {
+		buf.Write(mp3Frame)
+		buf.Write(make([]byte, 417-len(mp3Frame)))
+	}
+	return buf.Bytes()
+}
+
+func TestMP3(t *testing.T) {
+	data := makeMP3(5)
+	assert.Equal(t, nil, MP3(bytes.NewReader(data)))
+
+	// with ID3v2 and ID3v1 tags
+	id3v2 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x0a"), make([]byte, 10)...)
+	id3v1 := append([]byte("TAG"), make([]byte, 125)...)
+	tagged := append(append(append([]byte{}, id3v2...), data...), id3v1...)
+	assert.Equal(t, nil, MP3(bytes.NewReader(tagged)))
+
+	// last frame cut short
+	err := MP3(bytes.NewReader(data[:len(data)-10]))
+	assert.True(t, errors.Is(err, ErrTruncated), err)
+
+	// garbage in the middle of the stream, e.g. a wrongly decrypted chunk
+	corrupt := append([]byte{}, data...)
+	copy(corrupt[417*2:], bytes.Repeat([]byte{0x55}, 16))
+	err = MP3(bytes.NewReader(corrupt))
+	assert.True(t, errors.Is(err, ErrNoFrameSync), err)
+
+	err = MP3(bytes.NewReader(nil))
+	assert.True(t, errors.Is(err, ErrNoFrameSync), err)
+}
+
+func TestMPEGFrameLength(t *testing.T) {
+	for _, test := range []struct {
+		header []byte
+		length int
+		ok     bool
+	}{
+		{[]byte{0xff, 0xfb, 0x90, 0x00}, 417, true},
+		{[]byte{0xff, 0xfb, 0x92, 0x00}, 418, true}, // padded
+		{[]byte{0xff, 0xfb, 0xe4, 0x00}, 960, true}, // 320 kbit/s, 48 kHz
+		{[]byte{0xff, 0xf3, 0x90, 0x00}, 261, true}, // MPEG-2 80 kbit/s, 22.05 kHz
+		{[]byte{0xff, 0xfb, 0x00, 0x00}, 0, true},   // free format
+		{[]byte{0xff, 0xfb, 0xf0, 0x00}, 0, false},  // bad bitrate
+		{[]byte{0xff, 0xfb, 0x9c, 0x00}, 0, false},  // bad sample rate
+		{[]byte{0xff, 0xeb, 0x90, 0x00}, 0, false},  // reserved version
+		{[]byte{0x49, 0x44, 0x33, 0x04}, 0, false},  // not a frame
+	} {
+		length, ok := mpegFrameLength(test.header)
+		assert.Equal(t, test.ok, ok, "%x", test.header)
+		assert.Equal(t, test.length, length, "%x", test.header)
+	}
+}
+
+// writeFLAC encodes a short mono FLAC file of verbatim frames
+func writeFLAC(t *testing.T, path string) {
+	outFile, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outFile.Close()
+
+	info := &meta.StreamInfo{
+		BlockSizeMin:  1024,
+		BlockSizeMax:  1024,
+		SampleRate:    44100,
+		NChannels:     1,
+		BitsPerSample: 16,
+	}
+	enc, err := flac.NewEncoder(outFile, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		samples := make([]int32, 1024)
+		for j := range samples {
+			samples[j] = int32((i*1024+j)%2000 - 1000)
+		}
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         1024,
+				SampleRate:        44100,
+				Channels:          frame.ChannelsMono,
+				BitsPerSample:     16,
+			},
+			Subframes: []*frame.Subframe{{
+				SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+				Samples:   samples,
+				NSamples:  len(samples),
+			}},
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFLAC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.flac")
+	writeFLAC(t, path)
+
+	assert.Equal(t, nil, FLACFile(path))
+	data, err := ioutil.ReadFile(path)
+	assert.Equal(t, nil, err)
+
+	// the MD5 signature is at the end of STREAMINFO
+	md5Offset := 4 + 4 + 18
+	badMD5 := append([]byte{}, data...)
+	badMD5[md5Offset] ^= 0xff
+	err = FLAC(bytes.NewReader(badMD5))
+	assert.True(t, errors.Is(err, ErrMD5Mismatch), err)
+
+	// a corrupted sample fails the frame CRC
+	badFrame := append([]byte{}, data...)
+	badFrame[len(badFrame)-100] ^= 0xff
+	err = FLAC(bytes.NewReader(badFrame))
+	assert.True(t, errors.Is(err, ErrBadStream), err)
+
+	err = FLAC(bytes.NewReader(data[:len(data)-500]))
+	assert.NotEqual(t, nil, err)
+
+	err = FLAC(bytes.NewReader(makeMP3(2)))
+	assert.True(t, errors.Is(err, ErrBadStream), err)
+}
+
+func TestSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.mp3")
+	assert.Equal(t, nil, ioutil.WriteFile(path, makeMP3(2), 0644))
+
+	assert.Equal(t, nil, Size(path, 834))
+	assert.Equal(t, nil, Size(path, 0))
+	err = Size(path, 1000)
+	assert.True(t, errors.Is(err, ErrSizeMismatch), err)
+}
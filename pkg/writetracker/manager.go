@@ -0,0 +1,254 @@
+package writetracker
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	labelWidth = 32
+	barWidth   = 20
+)
+
+// Manager renders the progress of several concurrent downloads, one
+// line per active download plus an optional overall line. On a
+// terminal, the lines are redrawn in place using ANSI cursor movement;
+// otherwise a line is printed as each download finishes and
+// periodically while it runs. All methods are safe for concurrent use.
+type Manager struct {
+	Interval    time.Duration
+	LogInterval time.Duration
+
+	writer   io.Writer
+	terminal bool
+	bars     []*Bar
+	drawn    int
+	lastDraw time.Time
+
+	overallLabel string
+	overallTotal int
+	overallDone  int
+
+	mu sync.Mutex
+}
+
+// Bar is a single download line in a Manager. It is a Reporter, so it
+// can be passed to NewWriteTracker.
+type Bar struct {
+	Label    string
+	manager  *Manager
+	progress Progress
+	lastLog  time.Duration
+	logged   bool
+}
+
+// NewManager creates a Manager writing to f, which is redrawn in place
+// only if f is a terminal
+func NewManager(f *os.File) *Manager {
+	return NewManagerWriter(f, term.IsTerminal(int(f.Fd())))
+}
+
+// NewManagerWriter creates a Manager writing to w. terminal selects
+// whether ANSI redraws are used.
+func NewManagerWriter(w io.Writer, terminal bool) *Manager {
+	return &Manager{
+		Interval:    DefaultInterval,
+		LogInterval: DefaultLogInterval,
+		writer:      w,
+		terminal:    terminal,
+	}
+}
+
+// SetOverall shows an overall line counting completed tracks out of
+// total. A total of 0 hides the line.
+func (m *Manager) SetOverall(label string, total int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overallLabel = label
+	m.overallTotal = total
+	m.overallDone = 0
+	m.redraw(true)
+}
+
+// Skip counts a track towards the overall line without it ever having
+// had a bar, e.g. because it was already downloaded
+func (m *Manager) Skip() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overallDone++
+	m.logOverall()
+	m.redraw(true)
+}
+
+// Add adds a new bar
+func (m *Manager) Add(label string) *Bar {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bar := &Bar{
+		Label:   label,
+		manager: m,
+	}
+	m.bars = append(m.bars, bar)
+	m.redraw(true)
+	return bar
+}
+
+// Printf prints a message above the progress lines
+func (m *Manager) Printf(format string, a ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clear()
+	fmt.Fprintf(m.writer, format, a...)
+	m.redraw(true)
+}
+
+// Report updates the bar. A report marked as done removes the bar and
+// counts it towards the overall line.
+func (bar *Bar) Report(p Progress) {
+	m := bar.manager
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bar.progress = p
+
+	if p.Done {
+		m.remove(bar)
+		m.overallDone++
+		if !m.terminal {
+			fmt.Fprintf(m.writer, "%s: %s\n", bar.Label, FormatProgress(p))
+			m.logOverall()
+		}
+		m.redraw(true)
+		return
+	}
+
+	if !m.terminal {
+		if !bar.logged || p.Elapsed-bar.lastLog >= m.LogInterval {
+			bar.logged = true
+			bar.lastLog = p.Elapsed
+			fmt.Fprintf(m.writer, "%s: %s\n", bar.Label, FormatProgress(p))
+		}
+		return
+	}
+	m.redraw(false)
+}
+
+// Remove removes the bar without counting it as complete, e.g. after
+// the download failed
+func (bar *Bar) Remove() {
+	m := bar.manager
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(bar)
+	m.redraw(true)
+}
+
+// remove drops a bar from the active list. The lock must be held.
+func (m *Manager) remove(bar *Bar) {
+	for i, b := range m.bars {
+		if b == bar {
+			m.bars = append(m.bars[:i], m.bars[i+1:]...)
+			return
+		}
+	}
+}
+
+// logOverall prints the overall line when not on a terminal. The lock
+// must be held.
+func (m *Manager) logOverall() {
+	if !m.terminal && m.overallTotal > 0 {
+		fmt.Fprintln(m.writer, m.overallLine())
+	}
+}
+
+// clear moves the cursor back over the lines drawn last time, clearing
+// them. The lock must be held.
+func (m *Manager) clear() {
+	if !m.terminal || m.drawn == 0 {
+		return
+	}
+	fmt.Fprintf(m.writer, "\x1b[%dA", m.drawn)
+	for i := 0; i < m.drawn; i++ {
+		fmt.Fprint(m.writer, "\x1b[2K\n")
+	}
+	fmt.Fprintf(m.writer, "\x1b[%dA", m.drawn)
+	m.drawn = 0
+}
+
+// redraw draws all lines if attached to a terminal. Unless forced,
+// redraws are throttled to the interval. The lock must be held.
+func (m *Manager) redraw(force bool) {
+	if !m.terminal {
+		return
+	}
+	now := time.Now()
+	if !force && now.Sub(m.lastDraw) < m.Interval {
+		return
+	}
+	m.lastDraw = now
+
+	var lines []string
+	for _, bar := range m.bars {
+		lines = append(lines, bar.line())
+	}
+	if m.overallTotal > 0 {
+		lines = append(lines, m.overallLine())
+	}
+
+	if m.drawn > 0 {
+		fmt.Fprintf(m.writer, "\x1b[%dA", m.drawn)
+	}
+	for _, line := range lines {
+		fmt.Fprintf(m.writer, "\r\x1b[2K%s\n", line)
+	}
+	// clear any lines left over from a longer previous draw
+	for i := len(lines); i < m.drawn; i++ {
+		fmt.Fprint(m.writer, "\r\x1b[2K\n")
+	}
+	if extra := m.drawn - len(lines); extra > 0 {
+		fmt.Fprintf(m.writer, "\x1b[%dA", extra)
+	}
+	m.drawn = len(lines)
+}
+
+// line formats the bar for a terminal
+func (bar *Bar) line() string {
+	fraction := -1.0
+	if percent := bar.progress.Percent(); percent >= 0 {
+		fraction = percent / 100
+	}
+	return fmt.Sprintf("%-*s %s %s", labelWidth, truncate(bar.Label, labelWidth), drawBar(fraction), FormatProgress(bar.progress))
+}
+
+// overallLine formats the overall line. The lock must be held.
+func (m *Manager) overallLine() string {
+	fraction := float64(m.overallDone) / float64(m.overallTotal)
+	return fmt.Sprintf("%-*s %s %d/%d tracks", labelWidth, truncate(m.overallLabel, labelWidth), drawBar(fraction), m.overallDone, m.overallTotal)
+}
+
+// drawBar draws a bar filled to the given fraction, or an empty bar if
+// the fraction is negative (unknown)
+func drawBar(fraction float64) string {
+	if fraction < 0 {
+		return "[" + strings.Repeat(" ", barWidth) + "]"
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * barWidth)
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled) + "]"
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
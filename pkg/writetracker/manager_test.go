@@ -0,0 +1,89 @@
+package writetracker
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use, so
+// that the race detector only sees races in the Manager itself
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestManagerConcurrent(t *testing.T) {
+	for _, terminal := range []bool{true, false} {
+		var buf syncBuffer
+		m := NewManagerWriter(&buf, terminal)
+		m.SetOverall("Album", 8)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				tracker := NewWriteTracker(4096, m.Add("track"))
+				for j := 0; j < 16; j++ {
+					tracker.Write(make([]byte, 256))
+				}
+				tracker.Finish()
+			}(i)
+		}
+		wg.Wait()
+
+		m.mu.Lock()
+		assert.Equal(t, 0, len(m.bars), "finished bars should be removed")
+		assert.Equal(t, 8, m.overallDone)
+		m.mu.Unlock()
+
+		output := buf.String()
+		if terminal {
+			assert.Contains(t, output, "\x1b[")
+			assert.Contains(t, output, "8/8 tracks")
+		} else {
+			assert.NotContains(t, output, "\x1b[")
+			assert.NotContains(t, output, "\r")
+			assert.Equal(t, 8, strings.Count(output, "track: 4.1 kB / 4.1 kB (100.0%)"))
+		}
+	}
+}
+
+func TestManagerNonTerminalThrottle(t *testing.T) {
+	var buf syncBuffer
+	m := NewManagerWriter(&buf, false)
+	bar := m.Add("track")
+	bar.Report(Progress{Bytes: 1, Total: 10, Elapsed: time.Second})
+	bar.Report(Progress{Bytes: 2, Total: 10, Elapsed: 2 * time.Second})
+	bar.Report(Progress{Bytes: 10, Total: 10, Elapsed: 3 * time.Second, Done: true})
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+}
+
+func TestManagerPrintf(t *testing.T) {
+	var buf syncBuffer
+	m := NewManagerWriter(&buf, true)
+	m.Add("track")
+	m.Printf("hello\n")
+	output := buf.String()
+	// the bar is cleared before the message and drawn again after it
+	hello := strings.Index(output, "hello")
+	assert.True(t, strings.Index(output, "track") < hello)
+	assert.True(t, strings.LastIndex(output, "track") > hello)
+}
@@ -0,0 +1,60 @@
+package writetracker
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+// DefaultLogInterval is the minimum time between lines printed by a
+// LogReporter, or by a Manager that is not on a terminal
+const DefaultLogInterval = 5 * time.Second
+
+// FormatProgress formats a progress snapshot as a single line, e.g.
+// "12 MB / 30 MB (40.0%) 2.1 MB/s ETA 9s"
+func FormatProgress(p Progress) string {
+	var b strings.Builder
+	b.WriteString(humanize.Bytes(p.Bytes))
+	if p.Total > 0 {
+		fmt.Fprintf(&b, " / %s (%.1f%%)", humanize.Bytes(p.Total), p.Percent())
+	}
+	if rate := p.Rate(); rate > 0 {
+		fmt.Fprintf(&b, " %s/s", humanize.Bytes(uint64(rate)))
+	}
+	if eta := p.ETA(); eta > 0 && !p.Done {
+		fmt.Fprintf(&b, " ETA %s", eta.Round(time.Second))
+	}
+	return b.String()
+}
+
+// LogReporter prints a separate line at most once per Interval, plus
+// a final line when done
+type LogReporter struct {
+	Label    string
+	Interval time.Duration
+	writer   io.Writer
+	last     time.Duration
+	started  bool
+}
+
+// NewLogReporter creates a LogReporter writing to w
+func NewLogReporter(w io.Writer, label string) *LogReporter {
+	return &LogReporter{
+		Label:    label,
+		Interval: DefaultLogInterval,
+		writer:   w,
+	}
+}
+
+// Report prints a progress line if the interval has passed
+func (reporter *LogReporter) Report(p Progress) {
+	if !p.Done && reporter.started && p.Elapsed-reporter.last < reporter.Interval {
+		return
+	}
+	reporter.started = true
+	reporter.last = p.Elapsed
+	fmt.Fprintf(reporter.writer, "%s%s\n", reporter.Label, FormatProgress(p))
+}
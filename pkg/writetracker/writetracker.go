@@ -1,45 +1,136 @@
 package writetracker
 
 import (
-	"fmt"
-	"strings"
-
-	humanize "github.com/dustin/go-humanize"
+	"sync"
+	"time"
 )
 
 // https://progolang.com/how-to-download-files-in-go/
 
+// DefaultInterval is the minimum time between progress reports
+const DefaultInterval = 200 * time.Millisecond
+
+// Progress is a snapshot of a tracked transfer
+type Progress struct {
+	Bytes   uint64
+	Total   uint64 // 0 if the total is unknown
+	Elapsed time.Duration
+	Done    bool
+}
+
+// Rate returns the average transfer rate in bytes per second
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / p.Elapsed.Seconds()
+}
+
+// Percent returns how much of the total has been transferred, or -1
+// if the total is unknown
+func (p Progress) Percent() float64 {
+	if p.Total == 0 {
+		return -1
+	}
+	return 100 * float64(p.Bytes) / float64(p.Total)
+}
+
+// ETA estimates the time remaining, or -1 if it cannot be estimated
+func (p Progress) ETA() time.Duration {
+	rate := p.Rate()
+	if p.Total == 0 || rate == 0 {
+		return -1
+	}
+	if p.Bytes >= p.Total {
+		return 0
+	}
+	seconds := float64(p.Total-p.Bytes) / rate
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Reporter receives progress updates from a WriteTracker
+type Reporter interface {
+	Report(p Progress)
+}
+
+// ReporterFunc allows an ordinary function to be used as a Reporter
+type ReporterFunc func(p Progress)
+
+// Report calls the function
+func (f ReporterFunc) Report(p Progress) {
+	f(p)
+}
+
+// WriteTracker counts the bytes written to it and passes throttled
+// progress updates to a Reporter
 type WriteTracker struct {
-	bytes  uint64
-	Format string
+	Total    uint64
+	Interval time.Duration
+
+	bytes    uint64
+	reporter Reporter
+	start    time.Time
+	last     time.Time
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+// NewWriteTracker returns a pointer to a new write tracker that
+// reports to the given reporter. total is the expected number of
+// bytes, or 0 if it is unknown.
+func NewWriteTracker(total uint64, reporter Reporter) *WriteTracker {
+	return &WriteTracker{
+		Total:    total,
+		Interval: DefaultInterval,
+		reporter: reporter,
+		now:      time.Now,
+	}
 }
 
 // Write takes the input bytes and increments the total number of
-// bytes written
+// bytes written, reporting progress if the interval has passed
 func (tracker *WriteTracker) Write(b []byte) (int, error) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	now := tracker.now()
+	if tracker.start.IsZero() {
+		tracker.start = now
+	}
 	n := len(b)
 	tracker.bytes += uint64(n)
-	tracker.ShowProgress()
+
+	if now.Sub(tracker.last) >= tracker.Interval {
+		tracker.last = now
+		tracker.reporter.Report(tracker.progress(now, false))
+	}
 	return n, nil
 }
 
-// ShowProgress displays the current number of bytes written
-func (tracker *WriteTracker) ShowProgress() {
-	// reset line
-	fmt.Printf("\r%s", strings.Repeat(" ", 50))
+// Finish sends a final progress report marked as done
+func (tracker *WriteTracker) Finish() {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.reporter.Report(tracker.progress(tracker.now(), true))
+}
 
-	// print current progress
-	fmt.Printf(tracker.Format, humanize.Bytes(tracker.bytes))
+// Progress returns the current progress
+func (tracker *WriteTracker) Progress() Progress {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return tracker.progress(tracker.now(), false)
 }
 
-// NewWriteTracker returns a pointer to a new write tracker with a
-// custom format. Use an empty string for a default "downloaded"
-// formatter. %s is used for the bytes.
-func NewWriteTracker(format string) *WriteTracker {
-	if format == "" {
-		format = "\rDownloaded %s"
+// progress builds a snapshot. The lock must be held.
+func (tracker *WriteTracker) progress(now time.Time, done bool) Progress {
+	var elapsed time.Duration
+	if !tracker.start.IsZero() {
+		elapsed = now.Sub(tracker.start)
 	}
-	return &WriteTracker{
-		Format: format,
+	return Progress{
+		Bytes:   tracker.bytes,
+		Total:   tracker.Total,
+		Elapsed: elapsed,
+		Done:    done,
 	}
 }
@@ -0,0 +1,71 @@
+package writetracker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock that only moves when told to
+type fakeClock struct {
+	t time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return clock.t
+}
+
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.t = clock.t.Add(d)
+}
+
+func TestWriteTracker(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var reports []Progress
+	tracker := NewWriteTracker(1000, ReporterFunc(func(p Progress) {
+		reports = append(reports, p)
+	}))
+	tracker.now = clock.Now
+
+	// the first write always reports, the next within the interval
+	// does not
+	tracker.Write(make([]byte, 100))
+	clock.Advance(DefaultInterval / 2)
+	tracker.Write(make([]byte, 100))
+	assert.Equal(t, 1, len(reports))
+
+	clock.Advance(DefaultInterval)
+	tracker.Write(make([]byte, 100))
+	assert.Equal(t, 2, len(reports))
+
+	p := reports[1]
+	assert.Equal(t, uint64(300), p.Bytes)
+	assert.Equal(t, 30.0, p.Percent())
+	assert.InDelta(t, 1000, p.Rate(), 0.001)
+	assert.Equal(t, 700*time.Millisecond, p.ETA())
+
+	tracker.Finish()
+	assert.Equal(t, 3, len(reports))
+	assert.True(t, reports[2].Done)
+}
+
+func TestUnknownTotal(t *testing.T) {
+	p := Progress{Bytes: 100, Elapsed: time.Second}
+	assert.Equal(t, -1.0, p.Percent())
+	assert.Equal(t, time.Duration(-1), p.ETA())
+	assert.Equal(t, "100 B 100 B/s", FormatProgress(p))
+}
+
+func TestLogReporter(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := NewLogReporter(&buf, "")
+	reporter.Report(Progress{Bytes: 1, Elapsed: time.Second})
+	reporter.Report(Progress{Bytes: 2, Elapsed: 2 * time.Second})
+	reporter.Report(Progress{Bytes: 3, Elapsed: 7 * time.Second})
+	reporter.Report(Progress{Bytes: 4, Elapsed: 8 * time.Second, Done: true})
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"))
+	assert.NotContains(t, buf.String(), "\r")
+}